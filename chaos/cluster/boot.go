@@ -0,0 +1,209 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// bootDropInPrefix prefixes the names of the drop-in files perturbing units.
+	bootDropInPrefix = "ktestutil-chaos-"
+	// bootMarkerDir holds the markers of the units failing once, it must survive reboots.
+	bootMarkerDir = "/var/lib/ktestutil-chaos"
+	// bootIDPath holds the ID of the current boot.
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// BootPerturbation changes how a systemd unit starts on the next boots of a node.
+// It is installed as a drop-in of the unit, so it applies to every start of the unit until removed,
+// except FailOnce which only fails the first start after the next boot.
+// A unit can have several perturbations, but not the same one twice.
+type BootPerturbation struct {
+	// Unit is the perturbed unit eg. `kubelet.service`.
+	Unit string
+	// Delay delays the start of Unit, eg. to start docker after kubelet.
+	Delay time.Duration
+	// After orders Unit after these units if they are started too, eg. to start kubelet after `etcd-member.service`.
+	After []string
+	// FailOnce makes the first start of Unit after the next boot fail.
+	// Restarts of Unit before the reboot are not affected.
+	FailOnce bool
+}
+
+// name returns the name identifying the perturbation among the perturbations of its unit.
+func (p BootPerturbation) name() string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s|%s|%s|%t", p.Unit, p.Delay, strings.Join(p.After, " "), p.FailOnce)
+	return fmt.Sprintf("%s%08x", bootDropInPrefix, h.Sum32())
+}
+
+func (p BootPerturbation) dropInPath() string {
+	return path.Join("/etc/systemd/system", p.Unit+".d", p.name()+".conf")
+}
+
+// markerPath returns the path of the marker holding the boot ID the perturbation was installed in,
+// the unit fails once in any other boot.
+func (p BootPerturbation) markerPath() string {
+	return path.Join(bootMarkerDir, p.Unit+"."+p.name()+".fail-once")
+}
+
+// dropIn returns the content of the drop-in perturbing the unit.
+func (p BootPerturbation) dropIn() (string, error) {
+	if p.Unit == "" {
+		return "", fmt.Errorf("boot perturbation has no unit")
+	}
+	if p.Delay <= 0 && len(p.After) < 1 && !p.FailOnce {
+		return "", fmt.Errorf("boot perturbation of %s doesn't perturb anything", p.Unit)
+	}
+
+	lines := []string{"[Unit]"}
+	if len(p.After) > 0 {
+		lines = append(lines, "After="+strings.Join(p.After, " "))
+	}
+	lines = append(lines, "", "[Service]")
+	if p.FailOnce {
+		// `$$` is a literal `$` for systemd.
+		lines = append(lines, fmt.Sprintf(`ExecStartPre=/bin/sh -c 'if [ -e %[1]s ] && [ "$$(cat %[1]s)" != "$$(cat %[2]s)" ]; then rm -f %[1]s; exit 1; fi'`, p.markerPath(), bootIDPath))
+	}
+	if p.Delay > 0 {
+		// The delay must not count against the start timeout.
+		lines = append(lines, "TimeoutStartSec=0", fmt.Sprintf("ExecStartPre=/usr/bin/sleep %ds", int(p.Delay.Seconds())))
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// undoCmd returns the cmd removing the perturbation.
+func (p BootPerturbation) undoCmd() string {
+	return fmt.Sprintf("sudo rm -f %s %s && sudo systemctl daemon-reload", p.dropInPath(), p.markerPath())
+}
+
+// PerturbBoot installs perturbations on node, they apply from the next start of their units,
+// or from the next boot for FailOnce.
+// They stay installed, including across reboots, until removed with ClearBootPerturbations.
+func (cl *Cluster) PerturbBoot(ctx context.Context, node *v1.Node, perturbations ...BootPerturbation) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+	_, err = cl.installBootPerturbations(ctx, host, perturbations)
+	return err
+}
+
+// ClearBootPerturbations removes perturbations from node.
+func (cl *Cluster) ClearBootPerturbations(node *v1.Node, perturbations ...BootPerturbation) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+	return cl.removeBootPerturbations(host, perturbations, nil)
+}
+
+// installBootPerturbations installs perturbations on host,
+// it returns the IDs of their journal entries to pass to removeBootPerturbations.
+func (cl *Cluster) installBootPerturbations(ctx context.Context, host string, perturbations []BootPerturbation) ([]string, error) {
+	var (
+		cmds []string
+		ids  []string
+	)
+	if err := cl.checkBootPerturbations(ctx, host, perturbations); err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		if rerr := cl.removeBootPerturbations(host, perturbations[:len(ids)], ids); rerr != nil {
+			glog.Errorf("error cleaning up boot perturbations: %v", rerr)
+		}
+	}
+	for _, p := range perturbations {
+		data, err := p.dropIn()
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		id, err := cl.journal.record(host, "perturb "+p.Unit, p.undoCmd())
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		ids = append(ids, id)
+		tmpPath := path.Join("/tmp", p.Unit+"-"+p.name()+".conf")
+		if err := writeFile(cl.sshClient, host, tmpPath, data); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("node: %s error writing drop-in of %s: %v", host, p.Unit, err)
+		}
+		cmds = append(cmds,
+			fmt.Sprintf("sudo mkdir -p %s", path.Dir(p.dropInPath())),
+			fmt.Sprintf("sudo mv %s %s", tmpPath, p.dropInPath()),
+		)
+		if p.FailOnce {
+			cmds = append(cmds, fmt.Sprintf("sudo mkdir -p %s", bootMarkerDir), fmt.Sprintf("cat %s | sudo tee %s >/dev/null", bootIDPath, p.markerPath()))
+		}
+	}
+	cmd := strings.Join(append(cmds, "sudo systemctl daemon-reload"), " && ")
+
+	glog.V(4).Infof("node: %s installing %d boot perturbations", host, len(perturbations))
+	if stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("node: %s installing boot perturbations failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	return ids, nil
+}
+
+// checkBootPerturbations returns an error if perturbations are invalid or have duplicates,
+// or if any of them is already installed on host.
+func (cl *Cluster) checkBootPerturbations(ctx context.Context, host string, perturbations []BootPerturbation) error {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, p := range perturbations {
+		if _, err := p.dropIn(); err != nil {
+			return err
+		}
+		if seen[p.dropInPath()] {
+			return fmt.Errorf("duplicate boot perturbation of %s", p.Unit)
+		}
+		seen[p.dropInPath()] = true
+		paths = append(paths, p.dropInPath())
+	}
+	cmd := fmt.Sprintf("for f in %s; do [ ! -e $f ] || echo $f; done", strings.Join(paths, " "))
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd)
+	if err != nil {
+		return fmt.Errorf("node: %s error checking boot perturbations: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	if installed := strings.Fields(string(stdout)); len(installed) > 0 {
+		return fmt.Errorf("node: %s boot perturbations already installed: %s", host, strings.Join(installed, " "))
+	}
+	return nil
+}
+
+// removeBootPerturbations removes perturbations from host independent of any cancelled ctx.
+// ids are the journal entries of the perturbations, as returned by installBootPerturbations.
+// Without ids, eg. for perturbations installed by another call, the entries are found by their undo cmds.
+func (cl *Cluster) removeBootPerturbations(host string, perturbations []BootPerturbation, ids []string) error {
+	var errs []error
+	for i, p := range perturbations {
+		var id string
+		if i < len(ids) {
+			id = ids[i]
+		}
+		if err := cl.restore(host, id, "perturb "+p.Unit, p.undoCmd()); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if id == "" {
+			if err := cl.journal.resolve(host, p.undoCmd()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.NewAggregate(errs)
+}
@@ -0,0 +1,190 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// timeSyncUnits are the units that might keep the clock of a node in sync.
+var timeSyncUnits = []string{"systemd-timesyncd.service", "ntpd.service", "chronyd.service"}
+
+// ClockSkewResult is the outcome of skewing the clock of a node.
+// Skews are measured against the local clock over ssh,
+// so they are only accurate to the round trip of a ssh connection.
+type ClockSkewResult struct {
+	// Host is the address of the node.
+	Host string
+	// SyncUnits are the time sync units that were stopped and started again.
+	SyncUnits []string
+	// Before is the skew of the node's clock before the action.
+	Before time.Duration
+	// During is the skew of the node's clock while it was shifted.
+	During time.Duration
+	// After is the skew of the node's clock once time sync was restored.
+	After time.Duration
+	// Err is the reason the action failed, if it did.
+	Err error
+}
+
+// SkewClock stops time sync on nodes and shifts their clocks by offset, with a granularity of 1 sec,
+// for duration or until ctx is cancelled, whichever happens first.
+// It then shifts the clocks back and starts time sync again.
+// Stopping time sync and shifting the clocks are both journaled, so Recover shifts the clocks back
+// before it starts time sync again.
+func (cl *Cluster) SkewClock(ctx context.Context, nodes []*v1.Node, offset, duration time.Duration) ([]*ClockSkewResult, error) {
+	if err := cl.preflight(ctx); err != nil {
+		return nil, err
+	}
+	results := make([]*ClockSkewResult, len(nodes))
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = cl.skewClock(ctx, nodes[i], offset, duration)
+		}(i)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return results, errors.NewAggregate(errs)
+}
+
+func (cl *Cluster) skewClock(ctx context.Context, node *v1.Node, offset, duration time.Duration) (res *ClockSkewResult) {
+	res = &ClockSkewResult{}
+	host, err := hostForNode(node)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Host = host
+
+	if res.Before, res.Err = cl.measureSkew(ctx, host); res.Err != nil {
+		return res
+	}
+	if res.SyncUnits, res.Err = cl.activeUnits(ctx, host, timeSyncUnits); res.Err != nil {
+		return res
+	}
+
+	units := strings.Join(res.SyncUnits, " ")
+	syncAction := "stop time sync"
+	syncUndo := fmt.Sprintf("sudo systemctl start %s", units)
+	if len(res.SyncUnits) > 0 {
+		id, err := cl.mutate(ctx, host, syncAction, fmt.Sprintf("sudo systemctl stop %s", units), syncUndo)
+		if err != nil {
+			if rerr := cl.restore(host, id, syncAction, syncUndo); rerr != nil {
+				glog.Errorf("error cleaning up %s: %v", syncAction, rerr)
+			}
+			res.Err = err
+			return res
+		}
+		defer func() {
+			if err := cl.restore(host, id, syncAction, syncUndo); err != nil {
+				res.Err = appendErr(res.Err, err)
+				return
+			}
+			if after, err := cl.measureSkew(context.Background(), host); err != nil {
+				res.Err = appendErr(res.Err, err)
+			} else {
+				res.After = after
+			}
+		}()
+	} else {
+		glog.V(4).Infof("node: %s no time sync unit active", host)
+	}
+
+	secs := int64(offset / time.Second)
+	shiftAction := "shift clock"
+	shiftUndo := shiftClockCmd(-secs)
+	glog.V(4).Infof("node: %s shifting clock by %d secs", host, secs)
+	id, err := cl.mutate(ctx, host, shiftAction, shiftClockCmd(secs), shiftUndo)
+	if err != nil {
+		// date either sets the clock or fails, shifting back would skew a clock that was never shifted.
+		if rerr := cl.journal.resolveID(id); rerr != nil {
+			glog.Errorf("error resolving %s: %v", shiftAction, rerr)
+		}
+		res.Err = err
+		return res
+	}
+	if res.During, err = cl.measureSkew(ctx, host); err != nil {
+		glog.Errorf("%v", err)
+	}
+	glog.V(4).Infof("node: %s clock skewed by %s, holding for %s", host, res.During, duration)
+	hold(ctx, duration)
+
+	glog.V(4).Infof("node: %s shifting clock back by %d secs", host, -secs)
+	if err := cl.restore(host, id, shiftAction, shiftUndo); err != nil {
+		res.Err = err
+		return res
+	}
+	if len(res.SyncUnits) < 1 {
+		sctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		res.After, res.Err = cl.measureSkew(sctx, host)
+	}
+	return res
+}
+
+func shiftClockCmd(secs int64) string {
+	return fmt.Sprintf(`sudo date -s "%d seconds"`, secs)
+}
+
+// measureSkew returns how far the clock of host is ahead of the local clock.
+func (cl *Cluster) measureSkew(ctx context.Context, host string) (time.Duration, error) {
+	start := time.Now()
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, "date +%s.%N")
+	end := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error reading clock: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(stdout)), ".", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("node: %s unexpected clock output: %q", host, stdout)
+	}
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error parsing clock %q: %v", host, stdout, err)
+	}
+	nsec, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error parsing clock %q: %v", host, stdout, err)
+	}
+	local := start.Add(end.Sub(start) / 2)
+	return time.Unix(sec, nsec).Sub(local), nil
+}
+
+// activeUnits returns the units that are active on host.
+func (cl *Cluster) activeUnits(ctx context.Context, host string, units []string) ([]string, error) {
+	var active []string
+	for _, unit := range units {
+		state, _, err := cl.unitState(ctx, host, unit)
+		if err != nil {
+			return nil, err
+		}
+		if state == "active" {
+			active = append(active, unit)
+		}
+	}
+	return active, nil
+}
+
+// appendErr returns an error combining err and next.
+func appendErr(err, next error) error {
+	if err == nil {
+		return next
+	}
+	return fmt.Errorf("%v; %v", err, next)
+}
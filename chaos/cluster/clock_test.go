@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestSkewClock(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+
+	offset := 5 * time.Minute
+	results, err := cluster.SkewClock(context.Background(), []*v1.Node{cluster.Workers[0]}, offset, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := results[0]
+	t.Logf("node: %s skew before: %s during: %s after: %s", res.Host, res.Before, res.During, res.After)
+
+	if skew := res.During - res.Before; skew < offset-10*time.Second || skew > offset+10*time.Second {
+		t.Errorf("node: %s clock shifted by %s, expected %s", res.Host, skew, offset)
+	}
+	if skew := res.After - res.Before; skew < -10*time.Second || skew > 10*time.Second {
+		t.Errorf("node: %s clock still skewed by %s after restore", res.Host, skew)
+	}
+}
@@ -3,11 +3,16 @@ package cluster
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"fmt"
-	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/coreos/ktestutil/chaos/safety"
 	"github.com/coreos/ktestutil/utils"
 
 	"github.com/golang/glog"
@@ -36,12 +41,51 @@ ExecStart=/usr/bin/sleep %ds
 WantedBy=sysinit.target
 `
 	cmdEnableStallService  = "sudo mv /tmp/stall.service /etc/systemd/system/stall.service && sudo systemctl daemon-reload && sudo systemctl enable stall.service"
-	cmdDisableStallService = "sudo systemctl disable stall.service"
+	cmdDisableStallService = "[ ! -e /etc/systemd/system/stall.service ] || sudo systemctl disable stall.service"
 	cmdKernelPanic         = "nohup sh -c 'sleep 10 && echo b | sudo tee /proc/sysrq-trigger' >/dev/null 2>&1 &"
+	cmdKernelCrash         = "nohup sh -c 'sleep 10 && sudo sysctl -w kernel.panic=10 && echo c | sudo tee /proc/sysrq-trigger' >/dev/null 2>&1 &"
+	cmdSystemctlReboot     = "nohup sh -c 'sleep 10 && sudo systemctl reboot' >/dev/null 2>&1 &"
+	cmdSystemctlKexec      = "nohup sh -c 'sleep 10 && sudo systemctl kexec' >/dev/null 2>&1 &"
 	cmdSystemUp            = "sudo systemctl is-active kubelet"
+	cmdBootInfo            = "cat /proc/sys/kernel/random/boot_id /proc/uptime"
+
+	// cleanupTimeout is the time allowed for undoing chaos after an action was aborted.
+	cleanupTimeout = 30 * time.Second
+
+	defaultRebootDownTimeout  = 1 * time.Minute
+	defaultRebootUpTimeout    = 1 * time.Minute
+	defaultRebootPollInterval = 10 * time.Second
 )
 
-// Cluster is a simple abstraction that stores cluster nodes.
+// RebootResult is the outcome of a node reboot.
+type RebootResult struct {
+	// Host is the address of the node.
+	Host string
+	// Method is how the node was rebooted.
+	Method RebootMethod
+	// PrevBootID is the boot ID of the node before the reboot.
+	PrevBootID string
+	// BootID is the boot ID of the node after the reboot.
+	BootID string
+	// PrevUptime is the uptime of the node before the reboot.
+	PrevUptime time.Duration
+	// DrainTime is the time it took to drain the node, if it was drained.
+	DrainTime time.Duration
+	// DownTime is the time it took the node to go down once the reboot was issued.
+	DownTime time.Duration
+	// BootTime is the time it took the node to come back up with a new boot ID once it went down.
+	// It includes the reboot duration.
+	BootTime time.Duration
+	// KubeletTime is the time it took kubelet to become active once the node came back up.
+	KubeletTime time.Duration
+	// RecoveryTime is the time it took the node to recover to the Cluster's RecoveryLevel once kubelet was active.
+	// Drained nodes always recover to RecoveryNodeReady.
+	RecoveryTime time.Duration
+	// Err is the reason the reboot failed, if it did.
+	Err error
+}
+
+// Cluster is a simple abstraction that stores cluster nodes by role.
 // It allows rebooting the entire cluster / nodes.
 type Cluster struct {
 	// List of master nodes.
@@ -53,37 +97,77 @@ type Cluster struct {
 	// Defaults to 100%.
 	MaxDisruption intstr.IntOrString
 
-	client    kubernetes.Interface
-	sshClient *utils.SSHClient
-	sshConfig *utils.SSHConfig
+	client          kubernetes.Interface
+	sshClient       *utils.SSHClient
+	sshConfig       *utils.SSHConfig
+	powerController PowerController
+
+	rebootDownTimeout  time.Duration
+	rebootUpTimeout    time.Duration
+	rebootPollInterval time.Duration
+	recoveryLevel      RecoveryLevel
+	recoveryTimeout    time.Duration
+	drainTimeout       time.Duration
+	rebootMethod       RebootMethod
+	classifier         RoleClassifier
+	journal            *journal
+	skipRecover        bool
+	guard              *safety.Guard
+	etcdTLS            *tls.Config
+	// others holds the nodes that are neither masters nor workers, by role.
+	others map[Role][]*v1.Node
 }
 
 // New creates a new Cluster with the given options.
+// It undoes the chaos left behind by dead processes, see Recover, unless created WithoutAutoRecover.
 func New(client kubernetes.Interface, opts ...Options) (*Cluster, error) {
 	cl := &Cluster{
 		client:        client,
 		sshConfig:     &utils.SSHConfig{},
 		MaxDisruption: intstr.FromString("100%"),
+
+		rebootDownTimeout:  defaultRebootDownTimeout,
+		rebootUpTimeout:    defaultRebootUpTimeout,
+		rebootPollInterval: defaultRebootPollInterval,
+		recoveryLevel:      RecoveryKubelet,
+		recoveryTimeout:    defaultRecoveryTimeout,
+		classifier:         DefaultRoleClassifier,
+		journal:            &journal{path: filepath.Join(os.TempDir(), defaultJournalFile)},
 	}
 	for _, opt := range opts {
 		opt(cl)
 	}
 
 	cl.sshClient = utils.MustNewSSHClient(cl.sshConfig)
+	if cl.powerController == nil {
+		cl.powerController = NewSSHPowerController(cl.sshClient, defaultPowerOffDuration)
+	}
+	if pc, ok := cl.powerController.(*SSHPowerController); ok && pc.journal == nil {
+		pc.journal = cl.journal
+	}
+
+	if !cl.skipRecover {
+		if err := cl.Recover(); err != nil {
+			glog.Errorf("error undoing chaos of previous runs: %v", err)
+		}
+	}
 
 	nodelist, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
+	cl.others = make(map[Role][]*v1.Node)
 	for i := range nodelist.Items {
 		node := &nodelist.Items[i]
-		switch {
-		case utils.IsMaster(node):
+		role := cl.classifier(node)
+		glog.V(4).Infof("node: %s has role: %s", node.GetName(), role)
+		switch role {
+		case RoleMaster:
 			cl.Masters = append(cl.Masters, node)
-		case utils.IsWorker(node):
+		case RoleWorker:
 			cl.Workers = append(cl.Workers, node)
 		default:
-			return nil, fmt.Errorf("node: %s is neither master nor worker", node.GetName())
+			cl.others[role] = append(cl.others[role], node)
 		}
 	}
 
@@ -92,108 +176,311 @@ func New(client kubernetes.Interface, opts ...Options) (*Cluster, error) {
 
 // RebootAll reboots all the nodes that are accessible ie. have ExternalIP.
 func (cl *Cluster) RebootAll(rebootDuration time.Duration) error {
-	var hosts []string
-	hosts = append(hosts, hostsFromNodes(cl.Masters)...)
-	hosts = append(hosts, hostsFromNodes(cl.Workers)...)
+	_, err := cl.RebootAllWithCtx(context.Background(), rebootDuration)
+	return err
+}
+
+// RebootAllWithCtx reboots all the nodes that are accessible ie. have ExternalIP, with ctx.
+// It returns the RebootResult of every node that was rebooted.
+func (cl *Cluster) RebootAllWithCtx(ctx context.Context, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	hosts := hostsFromNodes(cl.allNodes())
 	if len(hosts) < 1 {
-		return fmt.Errorf("no nodes found that can be rebooted")
+		return nil, fmt.Errorf("no nodes found that can be rebooted")
 	}
 
 	glog.V(4).Infof("will reboot nodes: %s", hosts)
-	return cl.rebootHosts(hosts, rebootDuration)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
 }
 
 // RebootMasters reboots all the master nodes that are accessible ie. have ExternalIP.
 func (cl *Cluster) RebootMasters(rebootDuration time.Duration) error {
+	_, err := cl.RebootMastersWithCtx(context.Background(), rebootDuration)
+	return err
+}
+
+// RebootMastersWithCtx reboots all the master nodes that are accessible ie. have ExternalIP, with ctx.
+func (cl *Cluster) RebootMastersWithCtx(ctx context.Context, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
 	hosts := hostsFromNodes(cl.Masters)
 	if len(hosts) < 1 {
-		return fmt.Errorf("no nodes found that can be rebooted")
+		return nil, fmt.Errorf("no nodes found that can be rebooted")
 	}
 
 	glog.V(4).Infof("will reboot nodes: %s", hosts)
-	return cl.rebootHosts(hosts, rebootDuration)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
 }
 
 // RebootWorkers reboots all the worker nodes that are accessible ie. have ExternalIP.
 func (cl *Cluster) RebootWorkers(rebootDuration time.Duration) error {
+	_, err := cl.RebootWorkersWithCtx(context.Background(), rebootDuration)
+	return err
+}
+
+// RebootWorkersWithCtx reboots all the worker nodes that are accessible ie. have ExternalIP, with ctx.
+func (cl *Cluster) RebootWorkersWithCtx(ctx context.Context, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
 	hosts := hostsFromNodes(cl.Workers)
 	if len(hosts) < 1 {
-		return fmt.Errorf("no nodes found that can be rebooted")
+		return nil, fmt.Errorf("no nodes found that can be rebooted")
 	}
 
 	glog.V(4).Infof("will reboot nodes: %s", hosts)
-	return cl.rebootHosts(hosts, rebootDuration)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
 }
 
 // RebootNode reboots a node addressable with `host`.
 // Uses *Cluster sshClient.
 func (cl *Cluster) RebootNode(host string, rebootDuration time.Duration) error {
-	glog.V(4).Infof("node: %s enabling stall.service", host)
-	if err := cl.enableStallService(host, rebootDuration); err != nil {
-		return fmt.Errorf("node: %s error enabling stall.service: %v", host, err)
+	_, err := cl.RebootNodeWithCtx(context.Background(), host, rebootDuration)
+	return err
+}
+
+// RebootNodeWithCtx reboots a node addressable with `host`, with ctx.
+// The node is rebooted with the Cluster's RebootMethod unless opts override it.
+// If the Cluster drains nodes, the node is cordoned and drained before the reboot,
+// and uncordoned once it is Ready again. Drained nodes are rebooted with RebootSystemctl
+// unless a method was set explicitly.
+// The reboot is verified by comparing the node's boot ID before and after.
+// If the reboot fails or ctx is cancelled after stall.service was enabled,
+// it makes a best effort to disable stall.service again.
+// Boot perturbations set by opts are removed once the reboot is done or failed.
+func (cl *Cluster) RebootNodeWithCtx(ctx context.Context, host string, rebootDuration time.Duration, opts ...RebootOption) (*RebootResult, error) {
+	if err := cl.preflight(ctx); err != nil {
+		cfg := cl.rebootConfig(opts)
+		return &RebootResult{Host: host, Method: cfg.method, Err: err}, err
 	}
+	return cl.rebootNode(ctx, host, rebootDuration, opts...)
+}
 
-	glog.V(4).Infof("node: %s initiating kernel panic", host)
-	glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmdKernelPanic)
-	stdout, stderr, err := cl.sshClient.Exec(host, cmdKernelPanic)
-	if _, ok := err.(*ssh.ExitMissingError); ok {
-		// A terminated session is perfectly normal during reboot.
-		err = nil
+// rebootNode reboots a node addressable with `host` like RebootNodeWithCtx, without verifying the safety guard.
+// Batches of reboots verify it before each node, expecting the nodes they already disrupted.
+func (cl *Cluster) rebootNode(ctx context.Context, host string, rebootDuration time.Duration, opts ...RebootOption) (res *RebootResult, err error) {
+	cfg := cl.rebootConfig(opts)
+	res = &RebootResult{Host: host, Method: cfg.method}
+	defer func() { res.Err = err }()
+
+	res.PrevBootID, res.PrevUptime, err = cl.bootInfo(ctx, host)
+	if err != nil {
+		return res, fmt.Errorf("node: %s error reading boot id: %v", host, err)
 	}
+	glog.V(4).Infof("node: %s boot id: %s uptime: %s", host, res.PrevBootID, res.PrevUptime)
+
+	recoveryLevel := cl.recoveryLevel
+	if cl.drainTimeout > 0 {
+		// A drained node must be Ready before it is uncordoned.
+		recoveryLevel = RecoveryNodeReady
+
+		node := cl.nodeForHost(host)
+		if node == nil {
+			return res, fmt.Errorf("node: %s not found in cluster", host)
+		}
+		glog.V(4).Infof("node: %s cordoning", host)
+		cordoned, cerr := cl.setUnschedulable(node, true)
+		if cerr != nil {
+			return res, fmt.Errorf("node: %s error cordoning: %v", host, cerr)
+		}
+		if cordoned {
+			defer func() {
+				glog.V(4).Infof("node: %s uncordoning", host)
+				if _, uerr := cl.setUnschedulable(node, false); uerr != nil {
+					uerr = fmt.Errorf("node: %s error uncordoning: %v", host, uerr)
+					if err != nil {
+						uerr = fmt.Errorf("%v; %v", err, uerr)
+					}
+					err = uerr
+				}
+			}()
+		}
+
+		drainStart := time.Now()
+		if err := cl.drain(ctx, node); err != nil {
+			return res, fmt.Errorf("node: %s error draining: %v", host, err)
+		}
+		res.DrainTime = time.Since(drainStart)
+	}
+
+	glog.V(4).Infof("node: %s enabling stall.service", host)
+	stallID, err := enableStallService(ctx, cl.sshClient, cl.journal, host, rebootDuration)
 	if err != nil {
-		return fmt.Errorf("node: %s issuing reboot command failed\nstdout:%s\nstderr:%s", host, stdout, stderr)
+		return res, fmt.Errorf("node: %s error enabling stall.service: %v", host, err)
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		glog.V(4).Infof("node: %s reboot failed, disabling stall.service", host)
+		if derr := cl.cleanupStallService(host, stallID, rebootDuration); derr != nil {
+			err = fmt.Errorf("%v; %v", err, derr)
+		}
+	}()
+
+	if len(cfg.perturbations) > 0 {
+		// err must not be shadowed, the deferred removal reports to it.
+		var ids []string
+		if ids, err = cl.installBootPerturbations(ctx, host, cfg.perturbations); err != nil {
+			return res, err
+		}
+		defer func() {
+			glog.V(4).Infof("node: %s removing boot perturbations", host)
+			if derr := cl.removeBootPerturbations(host, cfg.perturbations, ids); derr != nil {
+				if err != nil {
+					derr = fmt.Errorf("%v; %v", err, derr)
+				}
+				err = derr
+			}
+		}()
 	}
 
-	if err := cl.waitForDown(host); err != nil {
-		return fmt.Errorf("node: %s didn't go down", host)
+	start := time.Now()
+	if err := cl.issueReboot(ctx, host, cfg.method); err != nil {
+		return res, err
 	}
 
+	if err := cl.waitForDown(ctx, host, res.PrevBootID); err != nil {
+		return res, fmt.Errorf("node: %s didn't go down: %v", host, err)
+	}
+	down := time.Now()
+	res.DownTime = down.Sub(start)
+	outageFromContext(ctx).setStatus(OutageActive)
+
 	glog.V(4).Infof("node: %s waiting %s for node to come back up", host, rebootDuration)
-	<-time.After(rebootDuration)
-	if err := cl.waitForUp(host); err != nil {
-		return fmt.Errorf("node: %s didn't come back up", host)
+	select {
+	case <-time.After(rebootDuration):
+	case <-ctx.Done():
+		return res, fmt.Errorf("node: %s reboot cancelled: %v", host, ctx.Err())
 	}
-	glog.V(4).Infof("node: %s reboot successful", host)
+	outageFromContext(ctx).setStatus(OutageRecovering)
+	res.BootID, err = cl.waitForBoot(ctx, host, res.PrevBootID)
+	if err != nil {
+		return res, fmt.Errorf("node: %s didn't come back up: %v", host, err)
+	}
+	booted := time.Now()
+	res.BootTime = booted.Sub(down)
+
+	if err := cl.waitForUp(ctx, host); err != nil {
+		return res, fmt.Errorf("node: %s kubelet didn't become active: %v", host, err)
+	}
+	kubeletUp := time.Now()
+	res.KubeletTime = kubeletUp.Sub(booted)
+
+	if err := cl.waitForRecovery(ctx, host, res.BootID, recoveryLevel); err != nil {
+		return res, fmt.Errorf("node: %s didn't recover: %v", host, err)
+	}
+	res.RecoveryTime = time.Since(kubeletUp)
+	glog.V(4).Infof("node: %s reboot successful, new boot id: %s", host, res.BootID)
 
 	glog.V(4).Infof("node: %s disabling stall.service", host)
-	if err := cl.disableStallService(host); err != nil {
-		return fmt.Errorf("node: %s error disabling stall.service: %v", host, err)
+	if err := disableStallService(ctx, cl.sshClient, cl.journal, host, stallID); err != nil {
+		return res, fmt.Errorf("node: %s error disabling stall.service: %v", host, err)
 	}
 
-	return nil
+	return res, nil
 }
 
-func (cl *Cluster) enableStallService(host string, stallVal time.Duration) error {
-	scp, err := utils.NewScpClient(cl.sshClient, host)
+// bootInfo returns the boot ID and the uptime of host.
+func (cl *Cluster) bootInfo(ctx context.Context, host string) (bootID string, uptime time.Duration, err error) {
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmdBootInfo)
 	if err != nil {
-		return fmt.Errorf("error creating scp conn: %v", err)
+		return "", 0, fmt.Errorf("%v\nstdout:%s\nstderr:%s", err, stdout, stderr)
 	}
-	f, err := scp.Create(stallServiceTmpPath)
+	// stdout is the boot ID on the first line and `<uptime> <idle time>` on the second.
+	fields := strings.Fields(string(stdout))
+	if len(fields) < 2 {
+		return "", 0, fmt.Errorf("unexpected output: %q", stdout)
+	}
+	secs, err := strconv.ParseFloat(fields[1], 64)
 	if err != nil {
-		return fmt.Errorf("error creating %s: %v", stallServiceTmpPath, err)
+		return "", 0, fmt.Errorf("error parsing uptime %q: %v", fields[1], err)
 	}
-	data := fmt.Sprintf(stallServiceTpl, int(stallVal.Seconds()))
-	if _, err := f.Write([]byte(data)); err != nil {
-		return fmt.Errorf("error writing to %s: %v", stallServiceTmpPath, err)
+
+	return fields[0], time.Duration(secs * float64(time.Second)), nil
+}
+
+// cleanupStallService disables stall.service on host independent of any cancelled ctx.
+// The node might be rebooting or stalled before sshd starts, so it is retried until the node
+// is reachable again, for at most the stall duration and the up timeout.
+// stall.service stays in the journal until it was disabled.
+func (cl *Cluster) cleanupStallService(host, id string, rebootDuration time.Duration) error {
+	var lastErr error
+	err := poll(context.Background(), cl.rebootPollInterval, rebootDuration+cl.rebootUpTimeout+cleanupTimeout, func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		if lastErr = disableStallService(ctx, cl.sshClient, cl.journal, host, id); lastErr != nil {
+			glog.V(4).Infof("node: %s stall.service not disabled yet: %v", host, lastErr)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
+
+// kernelPanic issues a reboot of host without a clean shutdown.
+func kernelPanic(ctx context.Context, sshClient *utils.SSHClient, host string) error {
+	glog.V(4).Infof("node: %s initiating kernel panic", host)
+	return execReboot(ctx, sshClient, host, cmdKernelPanic)
+}
+
+// execReboot executes a cmd on host that reboots it.
+func execReboot(ctx context.Context, sshClient *utils.SSHClient, host, cmd string) error {
+	glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmd)
+	stdout, stderr, err := sshClient.ExecWithCtx(ctx, host, cmd)
+	if _, ok := err.(*ssh.ExitMissingError); ok {
+		// A terminated session is perfectly normal during reboot.
+		err = nil
 	}
-	stdout, stderr, err := cl.sshClient.Exec(host, cmdEnableStallService)
 	if err != nil {
-		return fmt.Errorf("node: %s enable stall.service failed\nstdout:%s\nstderr:%s", host, stdout, stderr)
+		return fmt.Errorf("node: %s issuing reboot command failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
 	}
 	return nil
 }
 
-func (cl *Cluster) disableStallService(host string) error {
-	stdout, stderr, err := cl.sshClient.Exec(host, cmdDisableStallService)
+// enableStallService enables stall.service on host, it returns the ID of its journal entry.
+func enableStallService(ctx context.Context, sshClient *utils.SSHClient, j *journal, host string, stallVal time.Duration) (string, error) {
+	id, err := j.record(host, "stall.service", cmdDisableStallService)
 	if err != nil {
-		return fmt.Errorf("node: %s disabling stall.service failed\nstdout:%s\nstderr:%s", host, stdout, stderr)
+		return "", err
+	}
+	if err := writeFile(sshClient, host, stallServiceTmpPath, fmt.Sprintf(stallServiceTpl, int(stallVal.Seconds()))); err != nil {
+		return id, err
+	}
+	stdout, stderr, err := sshClient.ExecWithCtx(ctx, host, cmdEnableStallService)
+	if err != nil {
+		return id, fmt.Errorf("node: %s enable stall.service failed\nstdout:%s\nstderr:%s", host, stdout, stderr)
+	}
+	return id, nil
+}
+
+// writeFile writes data to the file at path on host with scp.
+func writeFile(sshClient *utils.SSHClient, host, path, data string) error {
+	scp, err := utils.NewScpClient(sshClient, host)
+	if err != nil {
+		return fmt.Errorf("error creating scp conn: %v", err)
+	}
+	defer scp.Close()
+	f, err := scp.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte(data)); err != nil {
+		return fmt.Errorf("error writing to %s: %v", path, err)
 	}
 	return nil
 }
 
-func (cl *Cluster) waitForUp(host string) error {
-	return wait.PollImmediate(10*time.Second, 1*time.Minute, func() (bool, error) {
-		stdout, stderr, err := cl.sshClient.Exec(host, cmdSystemUp)
+// disableStallService disables stall.service on host and removes the journal entry with id.
+func disableStallService(ctx context.Context, sshClient *utils.SSHClient, j *journal, host, id string) error {
+	stdout, stderr, err := sshClient.ExecWithCtx(ctx, host, cmdDisableStallService)
+	if err != nil {
+		return fmt.Errorf("node: %s disabling stall.service failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	return j.resolveID(id)
+}
+
+func (cl *Cluster) waitForUp(ctx context.Context, host string) error {
+	return poll(ctx, cl.rebootPollInterval, cl.rebootUpTimeout, func() (bool, error) {
+		stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmdSystemUp)
 		if err != nil {
 			glog.Errorf("node: %s %v: %v", host, err, stderr)
 			return false, nil
@@ -206,59 +493,195 @@ func (cl *Cluster) waitForUp(host string) error {
 	})
 }
 
-func (cl *Cluster) waitForDown(host string) error {
-	return wait.PollImmediate(10*time.Second, 1*time.Minute, func() (bool, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// waitForDown waits for host to become unreachable,
+// or to report a boot ID other than prevBootID ie. it rebooted between two polls.
+func (cl *Cluster) waitForDown(ctx context.Context, host, prevBootID string) error {
+	return poll(ctx, cl.rebootPollInterval, cl.rebootDownTimeout, func() (bool, error) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
-		_, _, err := cl.sshClient.ExecWithCtx(ctx, host, cmdSystemUp)
+		bootID, _, err := cl.bootInfo(ctx, host)
 		if err != nil {
 			return true, nil
 		}
-		return false, nil
+		return bootID != prevBootID, nil
+	})
+}
+
+// waitForBoot waits for host to be reachable with a boot ID other than prevBootID.
+// It returns the new boot ID.
+func (cl *Cluster) waitForBoot(ctx context.Context, host, prevBootID string) (string, error) {
+	var bootID string
+	err := poll(ctx, cl.rebootPollInterval, cl.rebootUpTimeout, func() (bool, error) {
+		id, _, err := cl.bootInfo(ctx, host)
+		if err != nil {
+			glog.Errorf("node: %s %v", host, err)
+			return false, nil
+		}
+		if id == prevBootID {
+			glog.Errorf("node: %s boot id unchanged, node didn't reboot yet", host)
+			return false, nil
+		}
+		bootID = id
+		return true, nil
 	})
+	return bootID, err
 }
 
-func (cl *Cluster) rebootHosts(hosts []string, rebootDuration time.Duration) error {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	for i := len(hosts) - 1; i > 0; i-- {
-		j := r.Intn(i + 1)
-		hosts[i], hosts[j] = hosts[j], hosts[i]
+// poll tries condition every interval until it returns true, an error,
+// timeout passes or ctx is cancelled.
+func poll(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		ok, err := condition()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return wait.ErrWaitTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+}
 
-	var errs []error
-	errCh := make(chan error)
-	errDone := make(chan struct{})
+// preflight refuses chaos if the cluster violates the checks of the safety guard.
+func (cl *Cluster) preflight(ctx context.Context) error {
+	if err := cl.guard.Verify(ctx); err != nil {
+		return fmt.Errorf("refusing chaos: %v", err)
+	}
+	return nil
+}
+
+// hold blocks until d passes, ctx is cancelled or the Outage of ctx is ended.
+// The Outage of ctx, if any, is active while holding.
+func hold(ctx context.Context, d time.Duration) {
+	o := outageFromContext(ctx)
+	o.setStatus(OutageActive)
+	defer o.setStatus(OutageRecovering)
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	case <-o.ended():
+	}
+}
+
+// rebootHosts reboots hosts in the batches of the RebootOrder, one batch after the other,
+// with at most MaxDisruption nodes rebooting at a time.
+// If a HealthGate is set, it must pass before each batch after the first one is started.
+// The safety guard is verified before each node is rebooted, expecting the nodes rebooted so far
+// to be cordoned or not Ready.
+// Once ctx is cancelled or the gate timed out no more reboots are started, and the in-flight ones are aborted.
+// It returns the results of the started reboots and an aggregate of the errors of every host.
+func (cl *Cluster) rebootHosts(ctx context.Context, hosts []string, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	cfg := cl.rebootConfig(opts)
+	maxParallel, err := intstr.GetValueFromIntOrPercent(&cfg.maxDisruption, len(hosts), true)
+	if err != nil {
+		return nil, fmt.Errorf("errors parsing max disruption: %v", err)
+	}
+	glog.V(4).Infof("parallel reboots: %d", maxParallel)
+
+	var (
+		results []*RebootResult
+		errs    []error
+	)
+	disrupted := &disruption{}
+	batches := cfg.order(cl, hosts)
+	for i, batch := range batches {
+		if i > 0 && cfg.healthGate != nil {
+			glog.V(4).Infof("waiting for health gate before batch: %d", i)
+			if err := cl.waitForHealthGate(ctx, cfg); err != nil {
+				for _, rest := range batches[i:] {
+					for _, host := range rest {
+						errs = append(errs, fmt.Errorf("node: %s reboot not started: health gate didn't pass: %v", host, err))
+					}
+				}
+				break
+			}
+		}
+
+		glog.V(4).Infof("rebooting batch: %d nodes: %s", i, batch)
+		batchResults, batchErrs := cl.rebootBatch(ctx, batch, maxParallel, disrupted, rebootDuration, opts...)
+		results = append(results, batchResults...)
+		errs = append(errs, batchErrs...)
+	}
+
+	return results, errors.NewAggregate(errs)
+}
+
+// rebootBatch reboots hosts in order, at most maxParallel at a time.
+// Once ctx is cancelled no more reboots are started, and the in-flight ones are aborted.
+func (cl *Cluster) rebootBatch(ctx context.Context, hosts []string, maxParallel int, disrupted *disruption, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, []error) {
+	var (
+		results []*RebootResult
+		errs    []error
+	)
+	resCh := make(chan *RebootResult)
+	resDone := make(chan struct{})
 	go func() {
-		for err := range errCh {
-			errs = append(errs, err)
+		for res := range resCh {
+			results = append(results, res)
+			if res.Err != nil {
+				errs = append(errs, res.Err)
+			}
 		}
-		errDone <- struct{}{}
+		resDone <- struct{}{}
 	}()
 
-	maxParallel, err := intstr.GetValueFromIntOrPercent(&cl.MaxDisruption, len(hosts), true)
-	if err != nil {
-		return fmt.Errorf("errors parsing max disruption: %v", err)
-	}
 	parallel := make(chan struct{}, maxParallel)
-	glog.V(4).Infof("parallel reboots: %d", maxParallel)
-	var wg sync.WaitGroup
+	var (
+		notStarted []error
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+	)
 	for i := range hosts {
+		select {
+		case parallel <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			mu.Lock()
+			for _, host := range hosts[i:] {
+				notStarted = append(notStarted, fmt.Errorf("node: %s reboot not started: %v", host, ctx.Err()))
+			}
+			mu.Unlock()
+			break
+		}
+
 		wg.Add(1)
-		parallel <- struct{}{}
 		go func(host string) {
 			defer wg.Done()
-			if err := cl.RebootNode(host, rebootDuration); err != nil {
-				errCh <- err
+			defer func() { <-parallel }()
+			if err := cl.preflight(disrupted.context(ctx)); err != nil {
+				mu.Lock()
+				notStarted = append(notStarted, fmt.Errorf("node: %s reboot not started: %v", host, err))
+				mu.Unlock()
+				return
 			}
-			<-parallel
+			// Added before the node is disrupted, so the guard of every other node expects it.
+			disrupted.add(cl.nodeForHost(host))
+			res, _ := cl.rebootNode(ctx, host, rebootDuration, opts...)
+			resCh <- res
 		}(hosts[i])
 	}
 	wg.Wait()
-	close(errCh)
+	close(resCh)
 	close(parallel)
-	<-errDone
+	<-resDone
 
-	return errors.NewAggregate(errs)
+	return results, append(errs, notStarted...)
 }
 
 func hostsFromNodes(nodes []*v1.Node) (hosts []string) {
@@ -273,3 +696,63 @@ func hostsFromNodes(nodes []*v1.Node) (hosts []string) {
 
 	return hosts
 }
+
+// hostForNode returns the address used to ssh into node.
+func hostForNode(n *v1.Node) (string, error) {
+	host := utils.ExternalIP(n)
+	if host == "" {
+		return "", fmt.Errorf("node: %s has no external IP", n.GetName())
+	}
+	return host, nil
+}
+
+// nodeIPs returns all the internal and external IPs of a node.
+func nodeIPs(n *v1.Node) (ips []string) {
+	seen := make(map[string]bool)
+	for _, addr := range n.Status.Addresses {
+		if addr.Type != v1.NodeInternalIP && addr.Type != v1.NodeExternalIP {
+			continue
+		}
+		if seen[addr.Address] {
+			continue
+		}
+		seen[addr.Address] = true
+		ips = append(ips, addr.Address)
+	}
+
+	return ips
+}
+
+// allNodes returns all the nodes known to the cluster, whatever their role.
+func (cl *Cluster) allNodes() []*v1.Node {
+	var nodes []*v1.Node
+	nodes = append(nodes, cl.Masters...)
+	nodes = append(nodes, cl.Workers...)
+	for _, role := range cl.otherRoles() {
+		nodes = append(nodes, cl.others[role]...)
+	}
+	return nodes
+}
+
+// disruption tracks the nodes a reboot of several nodes disrupted so far,
+// the safety guard expects them to be cordoned or not Ready.
+type disruption struct {
+	mu    sync.Mutex
+	nodes []string
+}
+
+func (d *disruption) add(node *v1.Node) {
+	if node == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.nodes = append(d.nodes, node.GetName())
+}
+
+// context returns a copy of ctx expecting the disrupted nodes.
+func (d *disruption) context(ctx context.Context) context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return safety.WithExpectedDisruption(ctx, d.nodes...)
+}
@@ -0,0 +1,100 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// ballastFile is the name of the file FillDisk creates.
+const ballastFile = ".ktestutil-chaos-ballast"
+
+// validDir matches the absolute paths that can be filled, they are interpolated in shell cmds.
+var validDir = regexp.MustCompile(`^/[A-Za-z0-9_./-]*$`)
+
+// FillDisk fills the filesystem holding dir on node, eg. `/var/lib/docker`, `/var/lib/etcd` or `/var/log`,
+// with a ballast file for duration or until ctx is cancelled, whichever happens first, and removes the file.
+// amount is either the usage of the filesystem to reach eg. `95%`,
+// or the size of the ballast file as a quantity eg. `5Gi`.
+// dir must be an absolute path.
+func (cl *Cluster) FillDisk(ctx context.Context, node *v1.Node, dir, amount string, duration time.Duration) error {
+	if !path.IsAbs(dir) || !validDir.MatchString(dir) {
+		return fmt.Errorf("invalid dir %q: must be an absolute path of letters, digits and _.-/", dir)
+	}
+	dir = path.Clean(dir)
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	size, err := cl.ballastSize(ctx, host, dir, amount)
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return fmt.Errorf("node: %s filesystem of %s is already above %s", host, dir, amount)
+	}
+
+	file := path.Join(dir, ballastFile)
+	action := "fill " + dir
+	undo := fmt.Sprintf("sudo rm -f %s", file)
+	glog.V(4).Infof("node: %s writing %d bytes to %s", host, size, file)
+	id, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo fallocate -l %d %s", size, file), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+	glog.V(4).Infof("node: %s %s filled, holding for %s", host, dir, duration)
+	hold(ctx, duration)
+
+	return cl.restore(host, id, action, undo)
+}
+
+// ballastSize returns the size in bytes of the ballast file needed to fill dir by amount.
+func (cl *Cluster) ballastSize(ctx context.Context, host, dir, amount string) (int64, error) {
+	if !strings.HasSuffix(amount, "%") {
+		q, err := resource.ParseQuantity(amount)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q: %v", amount, err)
+		}
+		if q.Value() <= 0 {
+			return 0, fmt.Errorf("invalid amount %q: quantity must be positive", amount)
+		}
+		return q.Value(), nil
+	}
+
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(amount, "%"), 64)
+	if err != nil || pct <= 0 || pct > 100 {
+		return 0, fmt.Errorf("invalid amount %q: percentage must be in (0, 100]", amount)
+	}
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, fmt.Sprintf("df -B1 --output=size,used %s | tail -n 1", dir))
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error getting usage of %s: %v\nstdout:%s\nstderr:%s", host, dir, err, stdout, stderr)
+	}
+	fields := strings.Fields(string(stdout))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("node: %s unexpected df output: %q", host, stdout)
+	}
+	total, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error parsing df output %q: %v", host, stdout, err)
+	}
+	used, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("node: %s error parsing df output %q: %v", host, stdout, err)
+	}
+	return int64(float64(total)*pct/100) - used, nil
+}
@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestFillDisk(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cluster.FillDisk(context.Background(), node, "/var/log", "0", 1*time.Minute); err == nil {
+		t.Fatal("expected a zero amount to be refused")
+	}
+
+	file := path.Join("/var/log", ballastFile)
+	exists := func() (bool, error) {
+		_, _, err := cluster.sshClient.Exec(host, fmt.Sprintf("test -e %s", file))
+		return err == nil, nil
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.FillDisk(context.Background(), node, "/var/log", "64Mi", 1*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, exists); err != nil {
+		t.Fatalf("node: %s ballast file %s was never written", host, file)
+	}
+	<-doneCh
+
+	if ok, _ := exists(); ok {
+		t.Fatalf("node: %s ballast file %s not removed", host, file)
+	}
+}
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/pkg/api/v1"
+	policy "k8s.io/client-go/pkg/apis/policy/v1beta1"
+)
+
+const (
+	// mirrorPodAnnotation marks the api objects of static pods.
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+	// evictionRetryInterval is the interval between evictions refused because of a PodDisruptionBudget.
+	evictionRetryInterval = 5 * time.Second
+)
+
+// setUnschedulable cordons or uncordons node.
+// It returns true if the node's schedulability was changed.
+func (cl *Cluster) setUnschedulable(node *v1.Node, unschedulable bool) (bool, error) {
+	var changed bool
+	err := utils.Retry(5, 1*time.Second, func() error {
+		n, err := cl.client.CoreV1().Nodes().Get(node.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if n.Spec.Unschedulable == unschedulable {
+			return nil
+		}
+		n.Spec.Unschedulable = unschedulable
+		if _, err := cl.client.CoreV1().Nodes().Update(n); err != nil {
+			return err
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+// drain evicts all the pods on node, except mirror pods and pods managed by a DaemonSet,
+// and waits for them to be deleted.
+// Evictions refused because of a PodDisruptionBudget are retried until the drain timeout.
+func (cl *Cluster) drain(ctx context.Context, node *v1.Node) error {
+	podList, err := cl.client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.GetName()).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("error listing pods: %v", err)
+	}
+	var pending []*v1.Pod
+	for i := range podList.Items {
+		p := &podList.Items[i]
+		if !evictable(p) {
+			continue
+		}
+		pending = append(pending, p)
+	}
+	evicted := append([]*v1.Pod(nil), pending...)
+
+	glog.V(4).Infof("node: %s evicting %d pods", node.GetName(), len(pending))
+	if err := poll(ctx, evictionRetryInterval, cl.drainTimeout, func() (bool, error) {
+		var blocked []*v1.Pod
+		for _, p := range pending {
+			err := cl.client.CoreV1().Pods(p.GetNamespace()).Evict(&policy.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      p.GetName(),
+					Namespace: p.GetNamespace(),
+				},
+			})
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+				glog.V(4).Infof("node: %s evicted pod: %s/%s", node.GetName(), p.GetNamespace(), p.GetName())
+			case apierrors.IsTooManyRequests(err):
+				glog.V(4).Infof("node: %s eviction of pod: %s/%s blocked by disruption budget", node.GetName(), p.GetNamespace(), p.GetName())
+				blocked = append(blocked, p)
+			default:
+				return false, fmt.Errorf("error evicting pod: %s/%s: %v", p.GetNamespace(), p.GetName(), err)
+			}
+		}
+		pending = blocked
+		return len(pending) == 0, nil
+	}); err != nil {
+		return fmt.Errorf("error evicting pods: %v", err)
+	}
+
+	return poll(ctx, evictionRetryInterval, cl.drainTimeout, func() (bool, error) {
+		for _, p := range evicted {
+			cur, err := cl.client.CoreV1().Pods(p.GetNamespace()).Get(p.GetName(), metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && cur.GetUID() != p.GetUID()) {
+				continue
+			}
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// evictable returns false for pods that a drain leaves on the node.
+func evictable(p *v1.Pod) bool {
+	if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+		return false
+	}
+	if _, ok := p.Annotations[mirrorPodAnnotation]; ok {
+		return false
+	}
+	for _, ref := range p.OwnerReferences {
+		if ref.Controller != nil && *ref.Controller && ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
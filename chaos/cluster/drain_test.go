@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/testworkload"
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainRebootWorkers(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	n, err := testworkload.NewNginx(client, metav1.NamespaceDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	cluster, err := New(client,
+		WithMaxDisruption(1),
+		WithDrain(5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster.RebootWorkersWithCtx(context.Background(), 1*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range cluster.Workers {
+		cur, err := client.CoreV1().Nodes().Get(node.GetName(), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cur.Spec.Unschedulable {
+			t.Fatalf("node: %s still cordoned after reboot", node.GetName())
+		}
+	}
+	if err := utils.Retry(10, 5*time.Second, n.IsReachable); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,746 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// etcdMemberUnit is the unit of static etcd members.
+	etcdMemberUnit = "etcd-member.service"
+	// etcdPodSelector selects the pods of self-hosted etcd members in kube-system.
+	etcdPodSelector = "app=etcd"
+	// etcdClientPort is the port etcd members serve clients on.
+	etcdClientPort = 2379
+	// etcdPeerPort is the port etcd members talk to each other on.
+	etcdPeerPort = 2380
+	// etcdRequestTimeout is the timeout of a single request to an etcd member.
+	etcdRequestTimeout = 5 * time.Second
+	// etcdTimeout is the time allowed for etcd to elect a leader or become healthy.
+	etcdTimeout = 5 * time.Minute
+	// etcdPollInterval is the interval between checks of the etcd leader and health.
+	etcdPollInterval = 1 * time.Second
+)
+
+// EtcdMember is a member of the etcd cluster backing the cluster.
+type EtcdMember struct {
+	// Name is the name of the node running a static member, or of the pod of a self-hosted member.
+	Name string
+	// Node is the node running the member.
+	Node *v1.Node
+	// Pod is the pod of a self-hosted member, nil for a static member.
+	Pod *v1.Pod
+	// ClientURL is the URL the member serves clients on, it is reached through a ssh tunnel to Node.
+	ClientURL string
+	// ID is the etcd ID of the member, empty if it couldn't be reached.
+	ID string
+}
+
+// EtcdResult is the outcome of a chaos action on etcd.
+type EtcdResult struct {
+	// PrevLeader is the member that was the leader before the action.
+	PrevLeader *EtcdMember
+	// Leader is the member elected during the action, or else the leader after the action.
+	Leader *EtcdMember
+	// Targets are the members that were killed, frozen or partitioned.
+	Targets []*EtcdMember
+	// ElectionTime is the time from the action until another leader was elected,
+	// zero for actions that don't require an election.
+	ElectionTime time.Duration
+	// RecoveryTime is the time from the end of the action until all members were healthy.
+	RecoveryTime time.Duration
+}
+
+// etcdStats is the subset of `/v2/stats/self` used.
+type etcdStats struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	State      string `json:"state"`
+	LeaderInfo struct {
+		Leader string `json:"leader"`
+	} `json:"leaderInfo"`
+}
+
+// EtcdMembers discovers the etcd members, either the masters and etcd nodes running etcd-member.service,
+// or the self-hosted etcd pods in kube-system if there are no static members.
+func (cl *Cluster) EtcdMembers(ctx context.Context) ([]*EtcdMember, error) {
+	members, err := cl.staticEtcdMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) < 1 {
+		if members, err = cl.selfHostedEtcdMembers(); err != nil {
+			return nil, err
+		}
+	}
+	if len(members) < 1 {
+		return nil, fmt.Errorf("no etcd members found")
+	}
+
+	for _, m := range members {
+		var stats etcdStats
+		if err := cl.etcdGet(ctx, m, "/v2/stats/self", &stats); err != nil {
+			glog.Errorf("etcd member: %s can't be reached: %v", m.Name, err)
+			continue
+		}
+		m.ID = stats.ID
+	}
+	return members, nil
+}
+
+func (cl *Cluster) staticEtcdMembers(ctx context.Context) ([]*EtcdMember, error) {
+	var members []*EtcdMember
+	for _, n := range append(append([]*v1.Node(nil), cl.Masters...), cl.Nodes(RoleEtcd)...) {
+		host, err := hostForNode(n)
+		if err != nil {
+			glog.V(4).Infof("skipping node: %s: %v", n.GetName(), err)
+			continue
+		}
+		state, _, err := cl.unitState(ctx, host, etcdMemberUnit)
+		if err != nil {
+			return nil, err
+		}
+		ips := nodeIPs(n)
+		if state != "active" || len(ips) < 1 {
+			continue
+		}
+		members = append(members, &EtcdMember{
+			Name:      n.GetName(),
+			Node:      n,
+			ClientURL: fmt.Sprintf("%s://%s:%d", cl.etcdScheme(), ips[0], etcdClientPort),
+		})
+	}
+	return members, nil
+}
+
+func (cl *Cluster) selfHostedEtcdMembers() ([]*EtcdMember, error) {
+	pods, err := cl.client.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{LabelSelector: etcdPodSelector})
+	if err != nil {
+		return nil, fmt.Errorf("error listing etcd pods: %v", err)
+	}
+	byName := make(map[string]*v1.Node)
+	for _, n := range cl.allNodes() {
+		byName[n.GetName()] = n
+	}
+
+	var members []*EtcdMember
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		n, ok := byName[p.Spec.NodeName]
+		if !ok || p.Status.PodIP == "" {
+			glog.V(4).Infof("skipping etcd pod: %s not running on a known node", p.GetName())
+			continue
+		}
+		members = append(members, &EtcdMember{
+			Name:      p.GetName(),
+			Node:      n,
+			Pod:       p,
+			ClientURL: fmt.Sprintf("%s://%s:%d", cl.etcdScheme(), p.Status.PodIP, etcdClientPort),
+		})
+	}
+	return members, nil
+}
+
+func (cl *Cluster) etcdScheme() string {
+	if cl.etcdTLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// etcdIPs returns all the IPs m may talk to its peers from,
+// the IPs of its node for static and host network members, or else the IP of its pod.
+func etcdIPs(m *EtcdMember) []string {
+	if m.Pod == nil || m.Pod.Spec.HostNetwork {
+		return nodeIPs(m.Node)
+	}
+	return []string{m.Pod.Status.PodIP}
+}
+
+// etcdSignalCmd returns the cmd sending sig, eg. `KILL`, to the processes of m only,
+// either to etcd-member.service or to the containers of the pod of m.
+// Other members on the same node are not affected.
+func (cl *Cluster) etcdSignalCmd(ctx context.Context, m *EtcdMember, sig string) (string, error) {
+	if m.Pod == nil {
+		return fmt.Sprintf("sudo systemctl kill --signal=SIG%s %s", sig, etcdMemberUnit), nil
+	}
+
+	containers, err := cl.etcdContainers(m)
+	if err != nil {
+		return "", err
+	}
+	var ids []string
+	for _, id := range containers {
+		ids = append(ids, id)
+	}
+
+	host, err := hostForNode(m.Node)
+	if err != nil {
+		return "", err
+	}
+	cmd := fmt.Sprintf("sudo docker inspect -f '{{.State.Pid}}' %s", strings.Join(ids, " "))
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd)
+	if err != nil {
+		return "", fmt.Errorf("node: %s error finding pids of etcd pod: %s: %v\nstdout:%s\nstderr:%s", host, m.Pod.GetName(), err, stdout, stderr)
+	}
+	var pids []string
+	for _, pid := range strings.Fields(string(stdout)) {
+		if pid != "0" {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) < 1 {
+		return "", fmt.Errorf("node: %s etcd pod: %s has no running processes", host, m.Pod.GetName())
+	}
+	return fmt.Sprintf("sudo kill -%s %s", sig, strings.Join(pids, " ")), nil
+}
+
+// etcdContainers returns the IDs of the running docker containers of the pod of m, by container name.
+func (cl *Cluster) etcdContainers(m *EtcdMember) (map[string]string, error) {
+	// The containers are restarted by kubelet, so the pod is fetched again for their current IDs.
+	p, err := cl.client.CoreV1().Pods(m.Pod.GetNamespace()).Get(m.Pod.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting etcd pod: %s: %v", m.Pod.GetName(), err)
+	}
+	if p.GetUID() != m.Pod.GetUID() {
+		return nil, fmt.Errorf("etcd pod: %s was replaced", m.Pod.GetName())
+	}
+	containers := make(map[string]string)
+	for _, s := range p.Status.ContainerStatuses {
+		if id := strings.TrimPrefix(s.ContainerID, "docker://"); id != "" && id != s.ContainerID && s.State.Running != nil {
+			containers[s.Name] = id
+		}
+	}
+	if len(containers) < 1 {
+		return nil, fmt.Errorf("etcd pod: %s has no running docker containers", p.GetName())
+	}
+	return containers, nil
+}
+
+// etcdGet decodes the JSON response to a GET of path on m into v.
+func (cl *Cluster) etcdGet(ctx context.Context, m *EtcdMember, path string, v interface{}) error {
+	host, err := hostForNode(m.Node)
+	if err != nil {
+		return err
+	}
+	tunnel, err := utils.NewTunnel(cl.sshClient, host)
+	if err != nil {
+		return fmt.Errorf("node: %s error creating ssh tunnel: %v", host, err)
+	}
+	defer tunnel.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial:            tunnel.Dial,
+			TLSClientConfig: cl.etcdTLS,
+		},
+		Timeout: etcdRequestTimeout,
+	}
+	req, err := http.NewRequest("GET", m.ClientURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error decoding %s response %q: %v", path, body, err)
+	}
+	return nil
+}
+
+// etcdHealthy returns an error if m doesn't report itself healthy.
+func (cl *Cluster) etcdHealthy(ctx context.Context, m *EtcdMember) error {
+	// health is a string or a bool depending on the etcd version.
+	var health struct {
+		Health json.RawMessage `json:"health"`
+	}
+	if err := cl.etcdGet(ctx, m, "/health", &health); err != nil {
+		return err
+	}
+	if strings.Trim(string(health.Health), `"`) != "true" {
+		return fmt.Errorf("etcd member: %s is not healthy", m.Name)
+	}
+	return nil
+}
+
+// EtcdLeader returns the leader according to the first member of members that can be reached.
+func (cl *Cluster) EtcdLeader(ctx context.Context, members []*EtcdMember) (*EtcdMember, error) {
+	var errs []error
+	for _, m := range members {
+		var stats etcdStats
+		if err := cl.etcdGet(ctx, m, "/v2/stats/self", &stats); err != nil {
+			errs = append(errs, fmt.Errorf("etcd member: %s: %v", m.Name, err))
+			continue
+		}
+		for _, l := range members {
+			if l.ID != "" && l.ID == stats.LeaderInfo.Leader {
+				return l, nil
+			}
+		}
+		errs = append(errs, fmt.Errorf("etcd member: %s reports unknown leader: %q", m.Name, stats.LeaderInfo.Leader))
+	}
+	return nil, fmt.Errorf("error finding etcd leader: %v", errors.NewAggregate(errs))
+}
+
+// waitForEtcdLeader waits for the members other than excluded to agree on a leader other than prev.
+func (cl *Cluster) waitForEtcdLeader(ctx context.Context, members []*EtcdMember, prev *EtcdMember, excluded []*EtcdMember) (*EtcdMember, error) {
+	others := without(members, excluded)
+	var leader *EtcdMember
+	err := poll(ctx, etcdPollInterval, etcdTimeout, func() (bool, error) {
+		l, err := cl.EtcdLeader(ctx, others)
+		if err != nil {
+			glog.V(4).Infof("no etcd leader yet: %v", err)
+			return false, nil
+		}
+		if l == prev {
+			glog.V(4).Infof("etcd leader is still: %s", l.Name)
+			return false, nil
+		}
+		leader = l
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no new etcd leader elected: %v", err)
+	}
+	glog.V(4).Infof("new etcd leader: %s", leader.Name)
+	return leader, nil
+}
+
+// waitForEtcdHealthy waits for all the members to be healthy.
+func (cl *Cluster) waitForEtcdHealthy(ctx context.Context, members []*EtcdMember) error {
+	return poll(ctx, etcdPollInterval, etcdTimeout, func() (bool, error) {
+		for _, m := range members {
+			if err := cl.etcdHealthy(ctx, m); err != nil {
+				glog.V(4).Infof("etcd member: %s not healthy yet: %v", m.Name, err)
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// KillEtcdLeader kills the etcd leader with SIGKILL, and waits for another leader to be elected
+// and for the killed member to be restarted, by systemd or kubelet, and healthy again.
+// It refuses to act on less than 3 members, as etcd would lose its quorum.
+func (cl *Cluster) KillEtcdLeader(ctx context.Context) (*EtcdResult, error) {
+	res, members, err := cl.etcdChaosStart(ctx)
+	if err != nil {
+		return res, err
+	}
+	if len(members) < 3 {
+		return res, fmt.Errorf("need atleast 3 etcd members to kill the leader, found: %d", len(members))
+	}
+	res.Targets = []*EtcdMember{res.PrevLeader}
+	return res, cl.killEtcdMembers(ctx, members, res)
+}
+
+// KillEtcdMinority kills the largest minority of the etcd members, chosen randomly on distinct nodes, with SIGKILL.
+// etcd must keep its quorum, it then waits for the killed members to be restarted and healthy again.
+func (cl *Cluster) KillEtcdMinority(ctx context.Context) (*EtcdResult, error) {
+	res, members, err := cl.etcdChaosStart(ctx)
+	if err != nil {
+		return res, err
+	}
+	n := (len(members) - 1) / 2
+	if n < 1 {
+		return res, fmt.Errorf("need atleast 3 etcd members to kill a minority, found: %d", len(members))
+	}
+	if res.Targets, err = pickMembers(members, n); err != nil {
+		return res, err
+	}
+	return res, cl.killEtcdMembers(ctx, members, res)
+}
+
+// KillEtcdMajority takes down a majority of the etcd members, chosen randomly on distinct nodes,
+// so that etcd loses its quorum.
+// Static members are stopped with systemctl for duration or until ctx is cancelled, whichever happens first,
+// and started again.
+// The containers of self-hosted members are killed with SIGKILL and restarted right away by kubelet,
+// they are down until kubelet restarted them, use FreezeEtcdMajority to hold them down for a duration.
+// It verifies etcd lost its quorum, and waits for all the members to be healthy again.
+func (cl *Cluster) KillEtcdMajority(ctx context.Context, duration time.Duration) (*EtcdResult, error) {
+	return cl.takeDownEtcdMajority(ctx, func(m *EtcdMember) error {
+		if m.Pod == nil {
+			return cl.stopEtcdMember(ctx, m, duration)
+		}
+		return cl.killEtcdPod(ctx, m)
+	})
+}
+
+// FreezeEtcdMajority takes down a majority of the etcd members, chosen randomly on distinct nodes,
+// so that etcd loses its quorum.
+// The members are frozen with SIGSTOP for duration or until ctx is cancelled, whichever happens first,
+// as opposed to KillEtcdMajority, self-hosted members are held down as well.
+// It then resumes them with SIGCONT, verifies etcd lost its quorum, and waits for all the members to be healthy again.
+func (cl *Cluster) FreezeEtcdMajority(ctx context.Context, duration time.Duration) (*EtcdResult, error) {
+	return cl.takeDownEtcdMajority(ctx, func(m *EtcdMember) error {
+		return cl.freezeEtcdMember(ctx, m, duration)
+	})
+}
+
+// takeDownEtcdMajority picks a majority of the members as targets and runs takeDown for each of them concurrently.
+// It verifies a remaining member, or else a target, reports itself unhealthy before all of them returned,
+// and waits for all the members to be healthy again.
+func (cl *Cluster) takeDownEtcdMajority(ctx context.Context, takeDown func(m *EtcdMember) error) (*EtcdResult, error) {
+	res, members, err := cl.etcdChaosStart(ctx)
+	if err != nil {
+		return res, err
+	}
+	if res.Targets, err = pickMembers(members, len(members)/2+1); err != nil {
+		return res, err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, m := range res.Targets {
+		wg.Add(1)
+		go func(m *EtcdMember) {
+			defer wg.Done()
+			if err := takeDown(m); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(m)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	probe := res.Targets[0]
+	if others := without(members, res.Targets); len(others) > 0 {
+		probe = others[0]
+	}
+	pctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-done:
+		case <-pctx.Done():
+		}
+		cancel()
+	}()
+	lostErr := poll(pctx, etcdPollInterval, etcdTimeout, func() (bool, error) {
+		return cl.etcdHealthy(pctx, probe) != nil, nil
+	})
+	cancel()
+	<-done
+	if err := errors.NewAggregate(errs); err != nil {
+		return res, err
+	}
+	if lostErr != nil {
+		return res, fmt.Errorf("etcd didn't lose its quorum, etcd member: %s stayed healthy: %v", probe.Name, lostErr)
+	}
+	glog.V(4).Infof("etcd lost its quorum, etcd member: %s is unhealthy", probe.Name)
+
+	return res, cl.etcdChaosEnd(ctx, members, res, time.Now())
+}
+
+// PartitionEtcdLeader drops the peer traffic between the etcd leader and the other members,
+// for duration or until ctx is cancelled, whichever happens first.
+// It waits for the other members to elect another leader, removes the partition
+// and waits for all the members to be healthy again.
+// It refuses to act on less than 3 members, as etcd would lose its quorum.
+func (cl *Cluster) PartitionEtcdLeader(ctx context.Context, duration time.Duration) (*EtcdResult, error) {
+	res, members, err := cl.etcdChaosStart(ctx)
+	if err != nil {
+		return res, err
+	}
+	if len(members) < 3 {
+		return res, fmt.Errorf("need atleast 3 etcd members to partition the leader, found: %d", len(members))
+	}
+	leader := res.PrevLeader
+	res.Targets = []*EtcdMember{leader}
+
+	leaderHost, err := hostForNode(leader.Node)
+	if err != nil {
+		return res, err
+	}
+	var rules []iptablesRule
+	for _, m := range without(members, res.Targets) {
+		host, err := hostForNode(m.Node)
+		if err != nil {
+			return res, err
+		}
+		for _, ip := range etcdIPs(m) {
+			rules = append(rules, etcdPeerRules(leaderHost, ip)...)
+		}
+		for _, ip := range etcdIPs(leader) {
+			rules = append(rules, etcdPeerRules(host, ip)...)
+		}
+	}
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cl.holdRules(ctx, rules, duration)
+	}()
+	res.Leader, err = cl.waitForEtcdLeader(ctx, members, leader, res.Targets)
+	if err == nil {
+		res.ElectionTime = time.Since(start)
+	}
+	if herr := <-errCh; herr != nil {
+		return res, herr
+	}
+	if err != nil {
+		return res, err
+	}
+
+	return res, cl.etcdChaosEnd(ctx, members, res, time.Now())
+}
+
+// etcdPeerRules returns the rules dropping the peer traffic from ip on host,
+// for both host network and pod network members.
+func etcdPeerRules(host, ip string) []iptablesRule {
+	var rules []iptablesRule
+	for _, chain := range []string{"INPUT", "FORWARD"} {
+		rules = append(rules, iptablesRule{
+			host:  host,
+			chain: chain,
+			spec:  fmt.Sprintf("-s %s -p tcp --dport %d -j DROP", ip, etcdPeerPort),
+		})
+	}
+	return rules
+}
+
+// etcdChaosStart verifies the cluster is safe and etcd healthy, and finds the members and the leader.
+func (cl *Cluster) etcdChaosStart(ctx context.Context) (*EtcdResult, []*EtcdMember, error) {
+	res := &EtcdResult{}
+	if err := cl.preflight(ctx); err != nil {
+		return res, nil, err
+	}
+	members, err := cl.EtcdMembers(ctx)
+	if err != nil {
+		return res, nil, err
+	}
+	for _, m := range members {
+		if err := cl.etcdHealthy(ctx, m); err != nil {
+			return res, nil, fmt.Errorf("refusing chaos on unhealthy etcd: %v", err)
+		}
+	}
+	if res.PrevLeader, err = cl.EtcdLeader(ctx, members); err != nil {
+		return res, nil, err
+	}
+	glog.V(4).Infof("etcd members: %d leader: %s", len(members), res.PrevLeader.Name)
+	return res, members, nil
+}
+
+// etcdChaosEnd waits for all members to be healthy after the chaos ended at end,
+// and finds the leader unless one was elected during the chaos.
+func (cl *Cluster) etcdChaosEnd(ctx context.Context, members []*EtcdMember, res *EtcdResult, end time.Time) error {
+	if err := cl.waitForEtcdHealthy(ctx, members); err != nil {
+		return fmt.Errorf("etcd didn't recover: %v", err)
+	}
+	res.RecoveryTime = time.Since(end)
+	glog.V(4).Infof("etcd recovered in %s", res.RecoveryTime)
+
+	if res.Leader != nil {
+		return nil
+	}
+	leader, err := cl.EtcdLeader(ctx, members)
+	if err != nil {
+		return err
+	}
+	res.Leader = leader
+	return nil
+}
+
+// killEtcdMembers kills the targets of res, waits for a new leader if the leader was killed,
+// and waits for all the members to be healthy again.
+func (cl *Cluster) killEtcdMembers(ctx context.Context, members []*EtcdMember, res *EtcdResult) error {
+	var killsLeader bool
+	for _, m := range res.Targets {
+		host, err := hostForNode(m.Node)
+		if err != nil {
+			return err
+		}
+		cmd, err := cl.etcdSignalCmd(ctx, m, "KILL")
+		if err != nil {
+			return err
+		}
+		glog.V(4).Infof("node: %s killing etcd member: %s with: '%s'", host, m.Name, cmd)
+		if stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd); err != nil {
+			return fmt.Errorf("node: %s killing etcd failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+		}
+		killsLeader = killsLeader || m == res.PrevLeader
+	}
+	killed := time.Now()
+
+	if killsLeader {
+		leader, err := cl.waitForEtcdLeader(ctx, members, res.PrevLeader, res.Targets)
+		if err != nil {
+			return err
+		}
+		res.Leader = leader
+		res.ElectionTime = time.Since(killed)
+	} else {
+		for _, m := range without(members, res.Targets) {
+			if err := cl.etcdHealthy(ctx, m); err != nil {
+				return fmt.Errorf("etcd lost its quorum: %v", err)
+			}
+		}
+	}
+
+	return cl.etcdChaosEnd(ctx, members, res, killed)
+}
+
+// freezeEtcdMember freezes m with SIGSTOP for duration or until ctx is cancelled,
+// whichever happens first, and resumes it with SIGCONT.
+func (cl *Cluster) freezeEtcdMember(ctx context.Context, m *EtcdMember, duration time.Duration) error {
+	cmd, err := cl.etcdSignalCmd(ctx, m, "STOP")
+	if err != nil {
+		return err
+	}
+	undo, err := cl.etcdSignalCmd(ctx, m, "CONT")
+	if err != nil {
+		return err
+	}
+	return cl.holdEtcdMember(ctx, m, "freeze etcd member "+m.Name, cmd, undo, duration)
+}
+
+// stopEtcdMember stops the static member m with systemctl for duration or until ctx is cancelled,
+// whichever happens first, and starts it again.
+func (cl *Cluster) stopEtcdMember(ctx context.Context, m *EtcdMember, duration time.Duration) error {
+	cmd := fmt.Sprintf("sudo systemctl stop %s", etcdMemberUnit)
+	undo := fmt.Sprintf("sudo systemctl start %s", etcdMemberUnit)
+	return cl.holdEtcdMember(ctx, m, "stop etcd member "+m.Name, cmd, undo, duration)
+}
+
+// holdEtcdMember executes cmd on the node of m for duration or until ctx is cancelled,
+// whichever happens first, and executes undo.
+func (cl *Cluster) holdEtcdMember(ctx context.Context, m *EtcdMember, action, cmd, undo string, duration time.Duration) error {
+	host, err := hostForNode(m.Node)
+	if err != nil {
+		return err
+	}
+	id, err := cl.mutate(ctx, host, action, cmd, undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+	glog.V(4).Infof("node: %s %s done, holding for %s", host, action, duration)
+	hold(ctx, duration)
+
+	return cl.restore(host, id, action, undo)
+}
+
+// killEtcdPod kills the containers of the self-hosted member m with SIGKILL,
+// and waits for kubelet to restart them.
+// The restarted containers are looked up with docker, as the API server is down while etcd has no quorum.
+func (cl *Cluster) killEtcdPod(ctx context.Context, m *EtcdMember) error {
+	host, err := hostForNode(m.Node)
+	if err != nil {
+		return err
+	}
+	containers, err := cl.etcdContainers(m)
+	if err != nil {
+		return err
+	}
+	cmd, err := cl.etcdSignalCmd(ctx, m, "KILL")
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("node: %s killing etcd member: %s with: '%s'", host, m.Name, cmd)
+	if stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd); err != nil {
+		return fmt.Errorf("node: %s killing etcd failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+
+	killed := make(map[string]bool)
+	var filters []string
+	for name, id := range containers {
+		killed[id] = true
+		// kubelet names docker containers k8s_<container>_<pod>_<namespace>_<uid>_<attempt>.
+		filters = append(filters, fmt.Sprintf("--filter name=k8s_%s_%s_%s_%s_", name, m.Pod.GetName(), m.Pod.GetNamespace(), m.Pod.GetUID()))
+	}
+	psCmd := fmt.Sprintf("sudo docker ps -q --no-trunc %s", strings.Join(filters, " "))
+	if err := poll(ctx, etcdPollInterval, etcdTimeout, func() (bool, error) {
+		stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, psCmd)
+		if err != nil {
+			glog.V(4).Infof("node: %s error listing containers of etcd member: %s: %v\nstderr:%s", host, m.Name, err, stderr)
+			return false, nil
+		}
+		var restarted int
+		for _, id := range strings.Fields(string(stdout)) {
+			if !killed[id] {
+				restarted++
+			}
+		}
+		return restarted >= len(containers), nil
+	}); err != nil {
+		return fmt.Errorf("node: %s etcd member: %s not restarted: %v", host, m.Name, err)
+	}
+	glog.V(4).Infof("node: %s etcd member: %s restarted", host, m.Name)
+	return nil
+}
+
+// pickMembers returns n members chosen randomly, no two of them on the same node.
+func pickMembers(members []*EtcdMember, n int) ([]*EtcdMember, error) {
+	shuffled := append([]*EtcdMember(nil), members...)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := len(shuffled) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+
+	var picked []*EtcdMember
+	nodes := make(map[string]bool)
+	for _, m := range shuffled {
+		if len(picked) == n {
+			break
+		}
+		if nodes[m.Node.GetName()] {
+			continue
+		}
+		nodes[m.Node.GetName()] = true
+		picked = append(picked, m)
+	}
+	if len(picked) < n {
+		return nil, fmt.Errorf("need %d etcd members on distinct nodes, found: %d", n, len(picked))
+	}
+	return picked, nil
+}
+
+// without returns the members that are not in excluded.
+func without(members, excluded []*EtcdMember) []*EtcdMember {
+	var res []*EtcdMember
+	for _, m := range members {
+		found := false
+		for _, e := range excluded {
+			if m == e {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, m)
+		}
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestKillEtcdLeader(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members, err := cluster.EtcdMembers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) < 3 {
+		t.Skip("need atleast 3 etcd members to elect another leader")
+	}
+
+	res, err := cluster.KillEtcdLeader(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Leader == res.PrevLeader {
+		t.Fatalf("etcd leader: %s was killed but is still the leader", res.PrevLeader.Name)
+	}
+	t.Logf("etcd leader: %s replaced by: %s in %s, recovered in %s", res.PrevLeader.Name, res.Leader.Name, res.ElectionTime, res.RecoveryTime)
+}
+
+func TestKillEtcdMajority(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := cluster.KillEtcdMajority(context.Background(), 1*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready after etcd recovered: %v", err)
+	}
+	t.Logf("etcd lost a majority of %d members, recovered in %s", len(res.Targets), res.RecoveryTime)
+}
+
+func TestFreezeEtcdMajority(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster.FreezeEtcdMajority(context.Background(), 1*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready after etcd recovered: %v", err)
+	}
+}
+
+func TestPickMembers(t *testing.T) {
+	nodeA := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	nodeB := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "b"}}
+	// self-hosted members sharing node a.
+	members := []*EtcdMember{
+		{Name: "etcd-0", Node: nodeA},
+		{Name: "etcd-1", Node: nodeA},
+		{Name: "etcd-2", Node: nodeB},
+	}
+
+	for i := 0; i < 20; i++ {
+		picked, err := pickMembers(members, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if picked[0].Node == picked[1].Node {
+			t.Fatalf("picked etcd members: %s and %s on the same node", picked[0].Name, picked[1].Name)
+		}
+	}
+	if _, err := pickMembers(members, 3); err == nil {
+		t.Fatal("expected picking 3 etcd members on 2 nodes to fail")
+	}
+}
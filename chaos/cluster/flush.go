@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/ktestutil/testworkload"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// reachabilityPollInterval is the interval between checks of a service after a flush.
+	reachabilityPollInterval = 1 * time.Second
+	// reachabilityTimeout is the time allowed for a service to be reachable again after a flush.
+	reachabilityTimeout = 5 * time.Minute
+)
+
+// FlushTarget is the networking state of a node that is flushed.
+type FlushTarget string
+
+const (
+	// FlushServiceRules flushes the `KUBE-SERVICES` and `KUBE-NODEPORTS` nat chains managed by kube-proxy.
+	FlushServiceRules FlushTarget = "service-rules"
+	// FlushConntrack flushes the conntrack table, the conntrack tool must be installed on the node.
+	FlushConntrack FlushTarget = "conntrack"
+)
+
+var flushCmds = map[FlushTarget]string{
+	FlushServiceRules: "sudo iptables -w -t nat -F KUBE-SERVICES && sudo iptables -w -t nat -F KUBE-NODEPORTS",
+	FlushConntrack:    "sudo conntrack -F",
+}
+
+// FlushResult is the outcome of a flush on a node.
+type FlushResult struct {
+	// Host is the address of the node.
+	Host string
+	// RecoveryTime is the time from the flush until the service was reachable again from the node.
+	RecoveryTime time.Duration
+	// Err is the reason the flush failed, if it did.
+	Err error
+}
+
+// FlushNetworking flushes target on nodes and measures, on each node,
+// how long it takes until the service of n is reachable again from the node.
+// There is nothing to restore, kube-proxy is expected to resync the rules by itself.
+func (cl *Cluster) FlushNetworking(ctx context.Context, nodes []*v1.Node, target FlushTarget, n *testworkload.Nginx) ([]*FlushResult, error) {
+	if err := cl.preflight(ctx); err != nil {
+		return nil, err
+	}
+	cmd, ok := flushCmds[target]
+	if !ok {
+		return nil, fmt.Errorf("unknown flush target: %s", target)
+	}
+	svc, err := cl.client.CoreV1().Services(n.Namespace).Get(n.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting service: %s/%s: %v", n.Namespace, n.Name, err)
+	}
+	if len(svc.Spec.Ports) < 1 {
+		return nil, fmt.Errorf("service: %s/%s has no ports", n.Namespace, n.Name)
+	}
+	url := fmt.Sprintf("http://%s:%d", svc.Spec.ClusterIP, svc.Spec.Ports[0].Port)
+
+	results := make([]*FlushResult, len(nodes))
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = cl.flush(ctx, nodes[i], cmd, url)
+		}(i)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return results, errors.NewAggregate(errs)
+}
+
+func (cl *Cluster) flush(ctx context.Context, node *v1.Node, cmd, url string) *FlushResult {
+	res := &FlushResult{}
+	host, err := hostForNode(node)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Host = host
+
+	if err := cl.reachable(ctx, host, url); err != nil {
+		res.Err = fmt.Errorf("node: %s service not reachable before flush: %v", host, err)
+		return res
+	}
+
+	glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmd)
+	if stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd); err != nil {
+		res.Err = fmt.Errorf("node: %s flush failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+		return res
+	}
+	flushed := time.Now()
+
+	if err := poll(ctx, reachabilityPollInterval, reachabilityTimeout, func() (bool, error) {
+		if err := cl.reachable(ctx, host, url); err != nil {
+			glog.V(4).Infof("node: %s service not reachable yet: %v", host, err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		res.Err = fmt.Errorf("node: %s service not reachable after flush: %v", host, err)
+		return res
+	}
+	res.RecoveryTime = time.Since(flushed)
+	glog.V(4).Infof("node: %s service reachable %s after flush", host, res.RecoveryTime)
+	return res
+}
+
+// reachable returns an error if url can't be fetched from host.
+func (cl *Cluster) reachable(ctx context.Context, host, url string) error {
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, fmt.Sprintf("curl -sS -m 2 -o /dev/null %s", url))
+	if err != nil {
+		return fmt.Errorf("%v\nstdout:%s\nstderr:%s", err, stdout, stderr)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreos/ktestutil/testworkload"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFlushNetworking(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	n, err := testworkload.NewNginx(client, metav1.NamespaceDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+
+	results, err := cluster.FlushNetworking(context.Background(), cluster.Workers, FlushServiceRules, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		t.Logf("node: %s service reachable %s after flush", res.Host, res.RecoveryTime)
+	}
+	if err := n.IsReachable(); err != nil {
+		t.Fatal(err)
+	}
+}
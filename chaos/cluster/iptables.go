@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/errors"
+)
+
+// iptablesComment tags every rule installed by this package,
+// so that they can be told apart from the rules managed by kube-proxy etc.
+const iptablesComment = "ktestutil-chaos"
+
+// iptablesRule is a single iptables rule on a host.
+type iptablesRule struct {
+	host string
+	// table defaults to `filter` if empty.
+	table string
+	chain string
+	// spec is the rule specification eg. `-s 10.0.0.1 -j DROP`.
+	spec string
+}
+
+func (r iptablesRule) cmd(op string) string {
+	table := r.table
+	if table == "" {
+		table = "filter"
+	}
+	return fmt.Sprintf("sudo iptables -w -t %s %s %s -m comment --comment %s %s", table, op, r.chain, iptablesComment, r.spec)
+}
+
+// insertRules inserts rules at the top of their chains.
+// It returns the IDs of the journal entries by host, to pass to deleteRules.
+// On error, rules that might have been inserted on the failing host are removed again.
+func (cl *Cluster) insertRules(ctx context.Context, rules []iptablesRule) (map[string]string, error) {
+	var inserted []iptablesRule
+	ids := make(map[string]string)
+	for host, hostRules := range rulesByHost(rules) {
+		var cmds []string
+		for _, r := range hostRules {
+			cmds = append(cmds, r.cmd("-I"))
+		}
+		id, err := cl.journal.record(host, "iptables", deleteRulesCmd(hostRules))
+		if err != nil {
+			if derr := cl.deleteRules(inserted, ids); derr != nil {
+				glog.Errorf("error cleaning up iptables rules: %v", derr)
+			}
+			return nil, err
+		}
+		ids[host] = id
+		cmd := strings.Join(cmds, " && ")
+		glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmd)
+		stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd)
+		if err != nil {
+			inserted = append(inserted, hostRules...)
+			if derr := cl.deleteRules(inserted, ids); derr != nil {
+				glog.Errorf("error cleaning up iptables rules: %v", derr)
+			}
+			return nil, fmt.Errorf("node: %s inserting iptables rules failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+		}
+		inserted = append(inserted, hostRules...)
+	}
+
+	return ids, nil
+}
+
+// deleteRules removes rules, it tries all the rules on a host even if some of them fail.
+// ids are the journal entries of the rules by host, as returned by insertRules.
+func (cl *Cluster) deleteRules(rules []iptablesRule, ids map[string]string) error {
+	var errs []error
+	for host, hostRules := range rulesByHost(rules) {
+		cmd := deleteRulesCmd(hostRules)
+		glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmd)
+		stdout, stderr, err := cl.sshClient.Exec(host, cmd)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node: %s deleting iptables rules failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr))
+			continue
+		}
+		if err := cl.journal.resolveID(ids[host]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.NewAggregate(errs)
+}
+
+// deleteRulesCmd returns the cmd deleting rules of a single host.
+// Rules that don't exist are skipped.
+func deleteRulesCmd(rules []iptablesRule) string {
+	var cmds []string
+	for _, r := range rules {
+		cmds = append(cmds, fmt.Sprintf("{ ! %s 2>/dev/null || %s || failed=1; }", r.cmd("-C"), r.cmd("-D")))
+	}
+	return fmt.Sprintf("failed=0; %s; exit $failed", strings.Join(cmds, "; "))
+}
+
+func rulesByHost(rules []iptablesRule) map[string][]iptablesRule {
+	m := make(map[string][]iptablesRule)
+	for _, r := range rules {
+		m[r.host] = append(m[r.host], r)
+	}
+	return m
+}
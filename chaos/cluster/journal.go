@@ -0,0 +1,277 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/errors"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// defaultJournalFile is the name of the journal file in the temp dir.
+const defaultJournalFile = "ktestutil-chaos-journal.json"
+
+// journalEntry is a chaos mutation applied to a node, and the cmd undoing it.
+type journalEntry struct {
+	ID      string       `json:"id"`
+	Host    string       `json:"host"`
+	Action  string       `json:"action"`
+	Undo    string       `json:"undo"`
+	Created time.Time    `json:"created"`
+	Owner   journalOwner `json:"owner"`
+}
+
+// journalOwner is the process that applied a mutation.
+type journalOwner struct {
+	PID      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+}
+
+// currentOwner returns the journalOwner of this process.
+func currentOwner() journalOwner {
+	hostname, _ := os.Hostname()
+	return journalOwner{PID: os.Getpid(), Hostname: hostname}
+}
+
+// dead returns true if the process that applied the mutation is known to have exited.
+// Processes on other hosts are never known to have exited.
+// Entries without owner were recorded by older versions, their process is considered dead.
+func (o journalOwner) dead() bool {
+	if o.PID == 0 {
+		return true
+	}
+	if o.Hostname != currentOwner().Hostname {
+		return false
+	}
+	return syscall.Kill(o.PID, 0) == syscall.ESRCH
+}
+
+// journal persists the chaos mutations that are not undone yet to a local file,
+// so that they can be undone even if the process that applied them died.
+//
+// Mutations are recorded before they are applied, so undo cmds must succeed
+// whether the mutation was applied or not.
+// The journal file can be shared by several processes, it is locked with flock while it is read or written.
+// A nil journal or one with an empty path records nothing.
+type journal struct {
+	path string
+
+	mu sync.Mutex
+}
+
+// lock locks the journal against this and other processes, it returns the func unlocking it.
+func (j *journal) lock() (func(), error) {
+	j.mu.Lock()
+	// The journal file is replaced on write, so a separate file is locked.
+	f, err := os.OpenFile(j.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		j.mu.Unlock()
+		return nil, fmt.Errorf("error locking journal %s: %v", j.path, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		j.mu.Unlock()
+		return nil, fmt.Errorf("error locking journal %s: %v", j.path, err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		j.mu.Unlock()
+	}, nil
+}
+
+// record adds a mutation of host, undone by executing undo on host.
+// It returns the ID of the entry, to resolve it with resolveID.
+func (j *journal) record(host, action, undo string) (string, error) {
+	if j == nil || j.path == "" {
+		return "", nil
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	entries, err := j.read()
+	if err != nil {
+		return "", err
+	}
+	id := utilrand.String(10)
+	entries = append(entries, journalEntry{
+		ID:      id,
+		Host:    host,
+		Action:  action,
+		Undo:    undo,
+		Created: time.Now(),
+		Owner:   currentOwner(),
+	})
+	return id, j.write(entries)
+}
+
+// resolve removes a mutation of host undone by undo, for mutations whose ID is not known.
+// Mutations with the same undo cmd can't be told apart, use resolveID whenever possible.
+func (j *journal) resolve(host, undo string) error {
+	return j.remove(func(e journalEntry) bool {
+		return e.Host == host && e.Undo == undo
+	})
+}
+
+// resolveID removes the mutation with id.
+func (j *journal) resolveID(id string) error {
+	if id == "" {
+		return nil
+	}
+	return j.remove(func(e journalEntry) bool {
+		return e.ID == id
+	})
+}
+
+// entries returns the mutations that are not undone yet, oldest first.
+func (j *journal) entries() ([]journalEntry, error) {
+	if j == nil || j.path == "" {
+		return nil, nil
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return j.read()
+}
+
+// remove removes the first entry matching match.
+func (j *journal) remove(match func(journalEntry) bool) error {
+	if j == nil || j.path == "" {
+		return nil
+	}
+
+	unlock, err := j.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if match(entries[i]) {
+			return j.write(append(entries[:i], entries[i+1:]...))
+		}
+	}
+	return nil
+}
+
+func (j *journal) read() ([]journalEntry, error) {
+	data, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading journal %s: %v", j.path, err)
+	}
+	var entries []journalEntry
+	if len(data) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing journal %s: %v", j.path, err)
+	}
+	return entries, nil
+}
+
+// write replaces the journal file atomically.
+func (j *journal) write(entries []journalEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path))
+	if err != nil {
+		return fmt.Errorf("error writing journal %s: %v", j.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing journal %s: %v", j.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing journal %s: %v", j.path, err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("error writing journal %s: %v", j.path, err)
+	}
+	return nil
+}
+
+// mutate records undo in the journal and executes cmd on host.
+// It returns the ID of the journal entry, also if cmd failed, to pass to restore.
+func (cl *Cluster) mutate(ctx context.Context, host, action, cmd, undo string) (string, error) {
+	id, err := cl.journal.record(host, action, undo)
+	if err != nil {
+		return "", err
+	}
+	glog.V(4).Infof("node: %s executing cmd: '%s'", host, cmd)
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd)
+	if err != nil {
+		return id, fmt.Errorf("node: %s %s failed: %v\nstdout:%s\nstderr:%s", host, action, err, stdout, stderr)
+	}
+	return id, nil
+}
+
+// restore executes undo on host, independent of any cancelled ctx,
+// and removes the journal entry with id.
+func (cl *Cluster) restore(host, id, action, undo string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	glog.V(4).Infof("node: %s executing cmd: '%s'", host, undo)
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, undo)
+	if err != nil {
+		return fmt.Errorf("node: %s undoing %s failed: %v\nstdout:%s\nstderr:%s", host, action, err, stdout, stderr)
+	}
+	return cl.journal.resolveID(id)
+}
+
+// Recover undoes the chaos mutations in the journal that were not undone
+// because the process applying them died.
+// Mutations of processes that are still running, or that ran on other hosts, are left alone.
+// Mutations are undone newest first, those that can't be undone stay in the journal for the next Recover.
+// It is called by New unless the Cluster was created WithoutAutoRecover.
+func (cl *Cluster) Recover() error {
+	entries, err := cl.journal.entries()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	// Newest first, mutations stacked on a node are undone in the reverse order they were applied.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !e.Owner.dead() {
+			glog.V(4).Infof("node: %s not undoing %s, process: %d on: %s is running", e.Host, e.Action, e.Owner.PID, e.Owner.Hostname)
+			continue
+		}
+		glog.V(4).Infof("node: %s undoing %s from %s", e.Host, e.Action, e.Created)
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, e.Host, e.Undo)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node: %s undoing %s failed: %v\nstdout:%s\nstderr:%s", e.Host, e.Action, err, stdout, stderr))
+			continue
+		}
+		if err := cl.journal.resolveID(e.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.NewAggregate(errs)
+}
@@ -0,0 +1,135 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecover(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "ktestutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.json")
+
+	cluster, err := New(client, WithJournal(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := hostsFromNodes(cluster.Workers)
+	if len(hosts) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+
+	// leave stall.service enabled, as a process dying mid reboot would.
+	if _, err := enableStallService(context.Background(), cluster.sshClient, cluster.journal, hosts[0], 1*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := cluster.journal.entries(); err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 journal entry, got: %v err: %v", entries, err)
+	}
+
+	// chaos of a running process is left alone.
+	cluster, err = New(client, WithJournal(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := cluster.journal.entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 journal entry, got: %v err: %v", entries, err)
+	}
+
+	// hand the entry to a process that exited.
+	dead := exec.Command("true")
+	if err := dead.Run(); err != nil {
+		t.Fatal(err)
+	}
+	entries[0].Owner.PID = dead.ProcessState.Pid()
+	if err := cluster.journal.write(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster, err = New(client, WithJournal(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := cluster.journal.entries(); err != nil || len(entries) != 0 {
+		t.Fatalf("expected empty journal, got: %v err: %v", entries, err)
+	}
+	if _, _, err := cluster.sshClient.Exec(hosts[0], "sudo systemctl is-enabled stall.service"); err == nil {
+		t.Fatalf("node: %s stall.service still enabled", hosts[0])
+	}
+}
+
+func TestJournalConcurrentRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ktestutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal.json")
+
+	// journals sharing a file, as Clusters of different processes do.
+	journals := []*journal{{path: path}, {path: path}}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(j *journal, i int) {
+			defer wg.Done()
+			if _, err := j.record("host", "action", fmt.Sprintf("undo %d", i)); err != nil {
+				t.Error(err)
+			}
+		}(journals[i%2], i)
+	}
+	wg.Wait()
+
+	entries, err := journals[0].entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 20 {
+		t.Fatalf("expected 20 journal entries, got: %d", len(entries))
+	}
+}
+
+func TestJournalResolveID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ktestutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	j := &journal{path: filepath.Join(dir, "journal.json")}
+
+	// a stopped and a killed unit are undone by the same cmd.
+	stopID, err := j.record("host", "stop kubelet.service", "sudo systemctl start kubelet.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	killID, err := j.record("host", "kill kubelet.service", "sudo systemctl start kubelet.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.resolveID(killID); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != stopID {
+		t.Fatalf("expected only entry: %s, got: %v", stopID, entries)
+	}
+}
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// RebootMethod defines how a node is rebooted.
+type RebootMethod string
+
+const (
+	// RebootSysrqReset resets the node immediately with sysrq `b`, without syncing or unmounting disks.
+	RebootSysrqReset RebootMethod = "sysrq-reset"
+	// RebootSysrqCrash crashes the kernel with sysrq `c`.
+	// A configured kdump captures the crash, otherwise the node reboots 10 secs after the panic.
+	RebootSysrqCrash RebootMethod = "sysrq-crash"
+	// RebootSystemctl cleanly shuts down and reboots the node with `systemctl reboot`.
+	RebootSystemctl RebootMethod = "systemctl-reboot"
+	// RebootKexec cleanly shuts down the node and boots into a new kernel with `systemctl kexec`.
+	RebootKexec RebootMethod = "systemctl-kexec"
+	// RebootPowerReset resets the node with the Cluster's PowerController.
+	RebootPowerReset RebootMethod = "power-reset"
+)
+
+// rebootCmds are the commands issuing the ssh based reboot methods.
+var rebootCmds = map[RebootMethod]string{
+	RebootSysrqReset: cmdKernelPanic,
+	RebootSysrqCrash: cmdKernelCrash,
+	RebootSystemctl:  cmdSystemctlReboot,
+	RebootKexec:      cmdSystemctlKexec,
+}
+
+// RebootOption sets options for a single reboot call.
+type RebootOption func(c *rebootConfig)
+
+// rebootConfig holds the options of a reboot call.
+type rebootConfig struct {
+	method        RebootMethod
+	maxDisruption intstr.IntOrString
+	perturbations []BootPerturbation
+	order         RebootOrder
+
+	healthGate        HealthGate
+	healthGateTimeout time.Duration
+}
+
+// RebootWithMethod defines how nodes are rebooted by this call,
+// overriding the Cluster's RebootMethod.
+func RebootWithMethod(m RebootMethod) RebootOption {
+	return func(c *rebootConfig) {
+		c.method = m
+	}
+}
+
+// RebootWithMaxDisruption defines the no. of parallel nodes rebooting in this call,
+// overriding the Cluster's MaxDisruption.
+// Accepts int eg. '3' for no. of nodes, and string '30%' for percent.
+func RebootWithMaxDisruption(d interface{}) RebootOption {
+	return func(c *rebootConfig) {
+		c.maxDisruption = toIntOrString(d)
+	}
+}
+
+// RebootWithBootPerturbations perturbs the boot of the nodes rebooted by this call.
+// The perturbations are removed once the nodes recovered, so units that fail once must be
+// restarted by systemd for the nodes to recover.
+func RebootWithBootPerturbations(perturbations ...BootPerturbation) RebootOption {
+	return func(c *rebootConfig) {
+		c.perturbations = append(c.perturbations, perturbations...)
+	}
+}
+
+// RebootWithOrder defines the order, and batches, the nodes are rebooted in by this call.
+// Nodes are rebooted in a single random batch by default.
+func RebootWithOrder(order RebootOrder) RebootOption {
+	return func(c *rebootConfig) {
+		c.order = order
+	}
+}
+
+// RebootWithHealthGate makes this call wait for gate to pass, for at most timeout,
+// before rebooting each batch after the first one.
+// No more batches are rebooted once the gate timed out.
+func RebootWithHealthGate(gate HealthGate, timeout time.Duration) RebootOption {
+	return func(c *rebootConfig) {
+		c.healthGate = gate
+		c.healthGateTimeout = timeout
+	}
+}
+
+// rebootConfig returns the Cluster's reboot defaults overridden by opts.
+func (cl *Cluster) rebootConfig(opts []RebootOption) *rebootConfig {
+	cfg := &rebootConfig{
+		method:        cl.rebootMethod,
+		maxDisruption: cl.MaxDisruption,
+		order:         RandomOrder(time.Now().UnixNano()),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.method == "" {
+		// A drained node is in maintenance, it is rebooted like an operator would.
+		cfg.method = RebootSysrqReset
+		if cl.drainTimeout > 0 {
+			cfg.method = RebootSystemctl
+		}
+	}
+	return cfg
+}
+
+// issueReboot reboots the node addressable with host using method.
+func (cl *Cluster) issueReboot(ctx context.Context, host string, method RebootMethod) error {
+	glog.V(4).Infof("node: %s rebooting with method: %s", host, method)
+	if method == RebootPowerReset {
+		node := cl.nodeForHost(host)
+		if node == nil {
+			return fmt.Errorf("node: %s not found in cluster", host)
+		}
+		if err := cl.powerController.Reset(node); err != nil {
+			return fmt.Errorf("node: %s error resetting power: %v", host, err)
+		}
+		return nil
+	}
+
+	cmd, ok := rebootCmds[method]
+	if !ok {
+		return fmt.Errorf("node: %s unknown reboot method: %s", host, method)
+	}
+	return execReboot(ctx, cl.sshClient, host, cmd)
+}
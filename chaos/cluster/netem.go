@@ -0,0 +1,159 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	cmdPrimaryInterface = "ip route show default | awk '{print $5; exit}'"
+	cmdRootQdisc        = "sudo tc qdisc show dev %s | grep -m1 ' root '"
+	// netemQdiscPattern matches the root qdiscs added by DegradeNetwork.
+	netemQdiscPattern = "^qdisc (netem|prio) 1: root"
+)
+
+// NetemSpec defines how the network of a node is degraded.
+// Percentages are given as eg. `10` for 10%.
+type NetemSpec struct {
+	// Delay is added to every outgoing packet.
+	Delay time.Duration
+	// Jitter is the random variation of Delay.
+	Jitter time.Duration
+	// Loss is the percentage of outgoing packets dropped.
+	Loss float64
+	// Duplicate is the percentage of outgoing packets sent twice.
+	Duplicate float64
+	// Corrupt is the percentage of outgoing packets with a flipped bit.
+	Corrupt float64
+	// Targets restricts the degradation to the traffic toward these CIDRs eg. `10.0.0.0/24`.
+	// All outgoing traffic, including ssh, is degraded if empty.
+	Targets []string
+}
+
+// NodeTargets returns the CIDRs matching the IPs of nodes, for use as NetemSpec Targets.
+func NodeTargets(nodes []*v1.Node) []string {
+	var targets []string
+	for _, n := range nodes {
+		for _, ip := range nodeIPs(n) {
+			targets = append(targets, ip+"/32")
+		}
+	}
+	return targets
+}
+
+// args returns the netem args of the spec.
+func (s NetemSpec) args() (string, error) {
+	var args []string
+	if s.Delay > 0 || s.Jitter > 0 {
+		args = append(args, fmt.Sprintf("delay %dms %dms", s.Delay/time.Millisecond, s.Jitter/time.Millisecond))
+	}
+	if s.Loss > 0 {
+		args = append(args, fmt.Sprintf("loss %g%%", s.Loss))
+	}
+	if s.Duplicate > 0 {
+		args = append(args, fmt.Sprintf("duplicate %g%%", s.Duplicate))
+	}
+	if s.Corrupt > 0 {
+		args = append(args, fmt.Sprintf("corrupt %g%%", s.Corrupt))
+	}
+	if len(args) < 1 {
+		return "", fmt.Errorf("netem spec doesn't degrade anything")
+	}
+	return strings.Join(args, " "), nil
+}
+
+// DegradeNetwork degrades the outgoing traffic on the primary interface of node with tc netem.
+// The degradation is removed when duration passes or ctx is cancelled, whichever happens first,
+// and the original root qdisc of the interface is restored.
+func (cl *Cluster) DegradeNetwork(ctx context.Context, node *v1.Node, spec NetemSpec, duration time.Duration) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+	args, err := spec.args()
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmdPrimaryInterface)
+	if err != nil {
+		return fmt.Errorf("node: %s error finding primary interface: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	dev := string(bytes.TrimSpace(stdout))
+	if dev == "" {
+		return fmt.Errorf("node: %s has no default route", host)
+	}
+	stdout, stderr, err = cl.sshClient.ExecWithCtx(ctx, host, fmt.Sprintf(cmdRootQdisc, dev))
+	if err != nil {
+		return fmt.Errorf("node: %s error reading root qdisc of %s: %v\nstdout:%s\nstderr:%s", host, dev, err, stdout, stderr)
+	}
+	orig := string(bytes.TrimSpace(stdout))
+	if regexp.MustCompile(netemQdiscPattern).MatchString(orig) {
+		return fmt.Errorf("node: %s network of %s is already degraded: %s", host, dev, orig)
+	}
+
+	var cmds []string
+	if len(spec.Targets) < 1 {
+		cmds = append(cmds, fmt.Sprintf("sudo tc qdisc replace dev %s root handle 1: netem %s", dev, args))
+	} else {
+		// Every priority goes to the first band, only the traffic matching the filters
+		// goes to the fourth band with netem.
+		cmds = append(cmds,
+			fmt.Sprintf("sudo tc qdisc replace dev %s root handle 1: prio bands 4 priomap%s", dev, strings.Repeat(" 0", 16)),
+			fmt.Sprintf("sudo tc qdisc add dev %s parent 1:4 handle 40: netem %s", dev, args),
+		)
+		for _, target := range spec.Targets {
+			cmds = append(cmds, fmt.Sprintf("sudo tc filter add dev %s protocol ip parent 1:0 prio 4 u32 match ip dst %s flowid 1:4", dev, target))
+		}
+	}
+	undo := fmt.Sprintf("if sudo tc qdisc show dev %s | grep -qE '%s'; then sudo tc qdisc del dev %s root", dev, netemQdiscPattern, dev)
+	if restore := restoreQdiscCmd(dev, orig); restore != "" {
+		undo += " && { " + restore + "; }"
+	}
+	undo += "; fi"
+
+	id, err := cl.mutate(ctx, host, "netem", strings.Join(cmds, " && "), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, "netem", undo); rerr != nil {
+			glog.Errorf("error cleaning up netem: %v", rerr)
+		}
+		return err
+	}
+	glog.V(4).Infof("node: %s network degraded, holding for %s", host, duration)
+	hold(ctx, duration)
+
+	return cl.restore(host, id, "netem", undo)
+}
+
+// restoreQdiscCmd returns the cmd restoring the root qdisc of dev described by orig,
+// a line of `tc qdisc show`, once the degradation was deleted.
+// Deleting the root qdisc restores the default qdisc of the kernel,
+// so only qdiscs that were configured, ie. with a handle other than `0:`, are added again.
+// Their child qdiscs and classes are not restored.
+func restoreQdiscCmd(dev, orig string) string {
+	// orig is eg. `qdisc fq_codel 8001: root refcnt 2 limit 10240p flows 1024`.
+	fields := strings.Fields(orig)
+	if len(fields) < 4 || fields[0] != "qdisc" || fields[3] != "root" || fields[2] == "0:" {
+		return ""
+	}
+	kind, handle, params := fields[1], fields[2], fields[4:]
+	if len(params) >= 2 && params[0] == "refcnt" {
+		params = params[2:]
+	}
+	add := fmt.Sprintf("sudo tc qdisc replace dev %s root handle %s %s", dev, handle, kind)
+	if len(params) < 1 {
+		return add
+	}
+	// The params shown are not always accepted back, the qdisc is then added with its defaults.
+	return fmt.Sprintf("%s %s || %s", add, strings.Join(params, " "), add)
+}
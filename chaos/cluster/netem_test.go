@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestDegradeNetwork(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := cluster.allNodes()
+	if len(nodes) < 2 {
+		t.Skip("need atleast 2 nodes to degrade the network between")
+	}
+	degraded, peer := nodes[len(nodes)-1], nodes[0]
+	host, err := hostForNode(degraded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerIPs := nodeIPs(peer)
+	if len(peerIPs) < 1 {
+		t.Fatalf("node: %s has no IPs", peer.GetName())
+	}
+
+	sshClient := utils.MustNewSSHClient(&utils.SSHConfig{Timeout: 10 * time.Second})
+	rootQdisc := func() string {
+		stdout, stderr, err := sshClient.Exec(host, fmt.Sprintf("tc qdisc show dev $(%s) | grep -m1 ' root '", cmdPrimaryInterface))
+		if err != nil {
+			t.Fatalf("node: %s error reading root qdisc: %v\nstderr:%s", host, err, stderr)
+		}
+		return strings.TrimSpace(string(stdout))
+	}
+	origQdisc := rootQdisc()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		spec := NetemSpec{
+			Delay:   2 * time.Second,
+			Targets: NodeTargets([]*v1.Node{peer}),
+		}
+		if err := cluster.DegradeNetwork(ctx, degraded, spec, 5*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// `ping -w 1` fails if no reply arrives within a sec, so it fails
+	// while the 2s delay is in place and succeeds once it is removed.
+	pingCmd := fmt.Sprintf("ping -c 1 -w 1 %s", peerIPs[0])
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, func() (bool, error) {
+		_, _, err := sshClient.Exec(host, pingCmd)
+		return err != nil, nil
+	}); err != nil {
+		t.Fatalf("node: %s traffic to node: %s not delayed", degraded.GetName(), peer.GetName())
+	}
+
+	cancel()
+	<-doneCh
+	if _, _, err := sshClient.Exec(host, pingCmd); err != nil {
+		t.Fatalf("node: %s traffic to node: %s still delayed: %v", degraded.GetName(), peer.GetName(), err)
+	}
+	if cur := rootQdisc(); cur != origQdisc {
+		t.Fatalf("node: %s root qdisc not restored, got: %q want: %q", degraded.GetName(), cur, origQdisc)
+	}
+}
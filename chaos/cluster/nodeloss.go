@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// nodeLossUnits are the units stopped to simulate the loss of a node.
+	nodeLossUnits = "kubelet.service docker.service"
+	// notReadyTimeout is the time allowed for the API server to mark a lost node not ready.
+	notReadyTimeout = 5 * time.Minute
+	// evictionTimeout is the time allowed for the pods of a lost node to be evicted.
+	evictionTimeout = 10 * time.Minute
+)
+
+// NodeLossResult holds the timings of the phases of a node loss.
+type NodeLossResult struct {
+	// Host is the address of the node.
+	Host string
+	// NotReadyTime is the time from stopping the node until the API server marked it not ready.
+	NotReadyTime time.Duration
+	// EvictionTime is the time from stopping the node until its pods were evicted,
+	// zero if eviction wasn't waited for.
+	EvictionTime time.Duration
+	// DownTime is the time the node was stopped.
+	DownTime time.Duration
+	// ReadyTime is the time from restarting the node until it was Ready with its kube-system pods.
+	ReadyTime time.Duration
+}
+
+// SimulateNodeLoss stops kubelet and the container runtime on node and waits for the API server
+// to mark the node `NotReady` or `Unknown` and, if waitEviction is true, for its pods to be evicted.
+// The node is kept down for duration from the moment it was stopped, or longer if the waits
+// require it, or until ctx is cancelled, whichever happens first.
+// It then starts the units again and waits for the node to recover.
+func (cl *Cluster) SimulateNodeLoss(ctx context.Context, node *v1.Node, duration time.Duration, waitEviction bool) (*NodeLossResult, error) {
+	if err := cl.preflight(ctx); err != nil {
+		return nil, err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	res := &NodeLossResult{Host: host}
+
+	pods, err := cl.client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.GetName()).String(),
+	})
+	if err != nil {
+		return res, fmt.Errorf("error listing pods: %v", err)
+	}
+	var lost []*v1.Pod
+	for i := range pods.Items {
+		if p := &pods.Items[i]; evictable(p) {
+			lost = append(lost, p)
+		}
+	}
+
+	action := "node loss"
+	undo := fmt.Sprintf("sudo systemctl start %s", nodeLossUnits)
+	id, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo systemctl stop %s", nodeLossUnits), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return res, err
+	}
+	stopped := time.Now()
+
+	err = cl.waitForNodeLoss(ctx, node, stopped, lost, waitEviction, res)
+	if err == nil {
+		glog.V(4).Infof("node: %s lost, holding for %s", host, duration-time.Since(stopped))
+		hold(ctx, duration-time.Since(stopped))
+	}
+
+	if rerr := cl.restore(host, id, action, undo); rerr != nil {
+		return res, rerr
+	}
+	started := time.Now()
+	res.DownTime = started.Sub(stopped)
+	if err != nil {
+		return res, err
+	}
+
+	if err := poll(context.Background(), cl.rebootPollInterval, cl.recoveryTimeout, func() (bool, error) {
+		if err := cl.nodeRecovered(node, ""); err != nil {
+			glog.V(4).Infof("node: %s not recovered yet: %v", host, err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return res, fmt.Errorf("node: %s didn't recover: %v", host, err)
+	}
+	res.ReadyTime = time.Since(started)
+	glog.V(4).Infof("node: %s recovered in %s", host, res.ReadyTime)
+	return res, nil
+}
+
+// waitForNodeLoss waits for the API server to notice the loss of node, stopped at stopped,
+// and, if waitEviction is true, for the pods to be evicted. It fills the timings of res.
+func (cl *Cluster) waitForNodeLoss(ctx context.Context, node *v1.Node, stopped time.Time, pods []*v1.Pod, waitEviction bool, res *NodeLossResult) error {
+	if err := poll(ctx, cl.rebootPollInterval, notReadyTimeout, func() (bool, error) {
+		n, err := cl.client.CoreV1().Nodes().Get(node.GetName(), metav1.GetOptions{})
+		if err != nil {
+			glog.Errorf("error getting node: %s: %v", node.GetName(), err)
+			return false, nil
+		}
+		for _, condition := range n.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status != v1.ConditionTrue {
+				glog.V(4).Infof("node: %s is %s: %s", node.GetName(), condition.Status, condition.Reason)
+				return true, nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return fmt.Errorf("node: %s wasn't marked not ready: %v", node.GetName(), err)
+	}
+	res.NotReadyTime = time.Since(stopped)
+
+	if !waitEviction {
+		return nil
+	}
+	if err := poll(ctx, cl.rebootPollInterval, evictionTimeout, func() (bool, error) {
+		for _, p := range pods {
+			cur, err := cl.client.CoreV1().Pods(p.GetNamespace()).Get(p.GetName(), metav1.GetOptions{})
+			if apierrors.IsNotFound(err) || (err == nil && (cur.GetUID() != p.GetUID() || cur.DeletionTimestamp != nil)) {
+				continue
+			}
+			glog.V(4).Infof("node: %s pod: %s/%s not evicted yet", node.GetName(), p.GetNamespace(), p.GetName())
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("node: %s pods weren't evicted: %v", node.GetName(), err)
+	}
+	res.EvictionTime = time.Since(stopped)
+	return nil
+}
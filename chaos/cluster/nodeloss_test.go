@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/testworkload"
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestSimulateNodeLoss(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 2 {
+		t.Skip("need atleast 2 workers to reschedule the evicted pods")
+	}
+
+	n, err := testworkload.NewNginx(client, metav1.NamespaceDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	// lose the node running nginx, so that its pods must be evicted and rescheduled.
+	var node *v1.Node
+	for _, w := range cluster.Workers {
+		for _, p := range n.Pods {
+			if p.Spec.NodeName == w.GetName() {
+				node = w
+			}
+		}
+	}
+	if node == nil {
+		t.Skip("nginx not running on a worker")
+	}
+
+	res, err := cluster.SimulateNodeLoss(context.Background(), node, 1*time.Minute, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NotReadyTime <= 0 || res.EvictionTime < res.NotReadyTime {
+		t.Fatalf("node: %s unexpected timings: not ready after %s, evicted after %s", node.GetName(), res.NotReadyTime, res.EvictionTime)
+	}
+	t.Logf("node: %s not ready after %s, evicted after %s, down for %s, ready after %s", node.GetName(), res.NotReadyTime, res.EvictionTime, res.DownTime, res.ReadyTime)
+
+	if err := utils.Retry(10, 5*time.Second, n.IsReachable); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,11 @@
 package cluster
 
 import (
+	"crypto/tls"
+	"time"
+
+	"github.com/coreos/ktestutil/chaos/safety"
+
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -31,13 +36,115 @@ func WithSSHIdentityKeyFile(path string) Options {
 // WithMaxDisruption defines the no. of parallel nodes rebooting.
 func WithMaxDisruption(d interface{}) Options {
 	return func(c *Cluster) {
-		var dis intstr.IntOrString
-		switch d.(type) {
-		case int:
-			dis = intstr.FromInt(d.(int))
-		case string:
-			dis = intstr.FromString(d.(string))
-		}
-		c.MaxDisruption = dis
+		c.MaxDisruption = toIntOrString(d)
+	}
+}
+
+func toIntOrString(d interface{}) intstr.IntOrString {
+	var dis intstr.IntOrString
+	switch d.(type) {
+	case int:
+		dis = intstr.FromInt(d.(int))
+	case string:
+		dis = intstr.FromString(d.(string))
+	}
+	return dis
+}
+
+// WithPowerController defines the PowerController used to shutdown and start nodes.
+// Defaults to a SSHPowerController.
+func WithPowerController(pc PowerController) Options {
+	return func(c *Cluster) {
+		c.powerController = pc
+	}
+}
+
+// WithRebootTimeouts defines how long a rebooting node may take to go down and to come back up,
+// and the interval between checks of the node's state.
+// Defaults to 1 min for both timeouts and 10 secs for the poll interval,
+// which are also used for values that are zero or negative.
+func WithRebootTimeouts(down, up, poll time.Duration) Options {
+	return func(c *Cluster) {
+		c.rebootDownTimeout = orDefault(down, defaultRebootDownTimeout)
+		c.rebootUpTimeout = orDefault(up, defaultRebootUpTimeout)
+		c.rebootPollInterval = orDefault(poll, defaultRebootPollInterval)
+	}
+}
+
+// orDefault returns d, or def if d is zero or negative.
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// WithRecoveryLevel defines how far a rebooted node must recover before its reboot is done,
+// and the time allowed for it once kubelet is active.
+// Defaults to RecoveryKubelet.
+func WithRecoveryLevel(level RecoveryLevel, timeout time.Duration) Options {
+	return func(c *Cluster) {
+		c.recoveryLevel = level
+		c.recoveryTimeout = timeout
+	}
+}
+
+// WithDrain makes reboots graceful: nodes are cordoned and drained using the eviction API
+// before they reboot, and uncordoned once they are Ready again.
+// timeout is the time allowed for evictions blocked by PodDisruptionBudgets.
+// Drained nodes are rebooted cleanly with RebootSystemctl unless a RebootMethod is set.
+func WithDrain(timeout time.Duration) Options {
+	return func(c *Cluster) {
+		c.drainTimeout = timeout
+	}
+}
+
+// WithRebootMethod defines how nodes are rebooted.
+// Defaults to RebootSysrqReset, or to RebootSystemctl if nodes are drained, see WithDrain.
+func WithRebootMethod(m RebootMethod) Options {
+	return func(c *Cluster) {
+		c.rebootMethod = m
+	}
+}
+
+// WithRoleClassifier defines how nodes are mapped to roles.
+// Defaults to DefaultRoleClassifier.
+func WithRoleClassifier(c RoleClassifier) Options {
+	return func(cl *Cluster) {
+		cl.classifier = c
+	}
+}
+
+// WithJournal defines the local file recording chaos that is not undone yet, see Cluster.Recover.
+// An empty path disables the journal.
+// Defaults to `ktestutil-chaos-journal.json` in the temp dir.
+func WithJournal(path string) Options {
+	return func(c *Cluster) {
+		c.journal.path = path
+	}
+}
+
+// WithoutAutoRecover stops New from undoing the chaos left behind by dead processes, see Cluster.Recover.
+func WithoutAutoRecover() Options {
+	return func(c *Cluster) {
+		c.skipRecover = true
+	}
+}
+
+// WithSafetyGuard makes every chaos action verify the checks of guard before it acts,
+// and refuse to act if the cluster violates any of them.
+// Reboots of several nodes verify the guard before each node, expecting the nodes
+// the reboot already disrupted to be cordoned or not Ready.
+func WithSafetyGuard(guard *safety.Guard) Options {
+	return func(c *Cluster) {
+		c.guard = guard
+	}
+}
+
+// WithEtcdTLS defines the TLS config, eg. the client certificates, used to reach etcd members.
+// etcd members are reached with plain http if it isn't set.
+func WithEtcdTLS(cfg *tls.Config) Options {
+	return func(c *Cluster) {
+		c.etcdTLS = cfg
 	}
 }
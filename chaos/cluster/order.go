@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// RebootOrder splits the hosts to reboot into batches, rebooted one after the other in the returned order.
+// Within a batch, hosts are rebooted in order, at most MaxDisruption at a time.
+type RebootOrder func(cl *Cluster, hosts []string) [][]string
+
+// RandomOrder reboots hosts in a single batch, shuffled with seed.
+func RandomOrder(seed int64) RebootOrder {
+	return func(cl *Cluster, hosts []string) [][]string {
+		shuffled := append([]string(nil), hosts...)
+		r := rand.New(rand.NewSource(seed))
+		for i := len(shuffled) - 1; i > 0; i-- {
+			j := r.Intn(i + 1)
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		}
+		return [][]string{shuffled}
+	}
+}
+
+// MastersFirst reboots the masters in a first batch and the other nodes in a second one.
+func MastersFirst(cl *Cluster, hosts []string) [][]string {
+	masters, others := cl.splitMasters(hosts)
+	return nonEmpty(masters, others)
+}
+
+// WorkersFirst reboots the nodes that aren't masters in a first batch and the masters in a second one.
+func WorkersFirst(cl *Cluster, hosts []string) [][]string {
+	masters, others := cl.splitMasters(hosts)
+	return nonEmpty(others, masters)
+}
+
+// OnePerZone reboots batches holding at most one node of every availability zone.
+// Nodes without a zone are considered to be in the same zone.
+func OnePerZone(cl *Cluster, hosts []string) [][]string {
+	byZone := make(map[string][]string)
+	var zones []string
+	for _, host := range hosts {
+		var zone string
+		if n := cl.nodeForHost(host); n != nil {
+			zone = n.GetLabels()[ZoneLabel]
+		}
+		if _, ok := byZone[zone]; !ok {
+			zones = append(zones, zone)
+		}
+		byZone[zone] = append(byZone[zone], host)
+	}
+	sort.Strings(zones)
+
+	var batches [][]string
+	for i := 0; ; i++ {
+		var batch []string
+		for _, zone := range zones {
+			if i < len(byZone[zone]) {
+				batch = append(batch, byZone[zone][i])
+			}
+		}
+		if len(batch) < 1 {
+			return batches
+		}
+		batches = append(batches, batch)
+	}
+}
+
+// ExplicitOrder reboots the nodes with the given names one batch each, in the given order.
+// Hosts of nodes that aren't named are rebooted in a last batch.
+func ExplicitOrder(names ...string) RebootOrder {
+	return func(cl *Cluster, hosts []string) [][]string {
+		byName := make(map[string]string)
+		for _, host := range hosts {
+			if n := cl.nodeForHost(host); n != nil {
+				byName[n.GetName()] = host
+			}
+		}
+		named := make(map[string]bool)
+		var batches [][]string
+		for _, name := range names {
+			if host, ok := byName[name]; ok && !named[host] {
+				named[host] = true
+				batches = append(batches, []string{host})
+			}
+		}
+		var rest []string
+		for _, host := range hosts {
+			if !named[host] {
+				rest = append(rest, host)
+			}
+		}
+		return nonEmpty(append(batches, rest)...)
+	}
+}
+
+// splitMasters splits hosts into the hosts of masters and the others.
+func (cl *Cluster) splitMasters(hosts []string) (masters, others []string) {
+	for _, host := range hosts {
+		if n := cl.nodeForHost(host); n != nil && cl.isMaster(n) {
+			masters = append(masters, host)
+			continue
+		}
+		others = append(others, host)
+	}
+	return masters, others
+}
+
+func nonEmpty(batches ...[]string) [][]string {
+	var res [][]string
+	for _, b := range batches {
+		if len(b) > 0 {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// HealthGate returns an error while the cluster isn't healthy enough to reboot the next batch of nodes.
+type HealthGate func(ctx context.Context, cl *Cluster) error
+
+// NodesReady is a HealthGate passing once all the nodes of the cluster are Ready.
+func NodesReady(ctx context.Context, cl *Cluster) error {
+	nodes, err := cl.client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %v", err)
+	}
+	var notReady []string
+	for i := range nodes.Items {
+		if !utils.IsNodeReady(&nodes.Items[i]) {
+			notReady = append(notReady, nodes.Items[i].GetName())
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("nodes: %s are not ready", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+// EtcdHealthy is a HealthGate passing once all the etcd members are healthy according to the API server.
+func EtcdHealthy(ctx context.Context, cl *Cluster) error {
+	statuses, err := cl.client.CoreV1().ComponentStatuses().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing component statuses: %v", err)
+	}
+	var found bool
+	for _, cs := range statuses.Items {
+		if !strings.HasPrefix(cs.GetName(), "etcd-") {
+			continue
+		}
+		found = true
+		for _, c := range cs.Conditions {
+			if c.Type == v1.ComponentHealthy && c.Status != v1.ConditionTrue {
+				return fmt.Errorf("%s is not healthy: %s", cs.GetName(), c.Error)
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no etcd component status found")
+	}
+	return nil
+}
+
+// AllGates is a HealthGate passing once all gates pass.
+func AllGates(gates ...HealthGate) HealthGate {
+	return func(ctx context.Context, cl *Cluster) error {
+		for _, gate := range gates {
+			if err := gate(ctx, cl); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// waitForHealthGate waits for the health gate of cfg to pass.
+func (cl *Cluster) waitForHealthGate(ctx context.Context, cfg *rebootConfig) error {
+	return poll(ctx, cl.rebootPollInterval, cfg.healthGateTimeout, func() (bool, error) {
+		if err := cfg.healthGate(ctx, cl); err != nil {
+			glog.V(4).Infof("health gate not passed yet: %v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+}
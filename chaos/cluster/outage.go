@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// OutageStatus is the phase of an Outage.
+type OutageStatus string
+
+const (
+	// OutageStarting is an outage whose fault is being put in place.
+	OutageStarting OutageStatus = "starting"
+	// OutageActive is an outage whose fault is in place.
+	OutageActive OutageStatus = "active"
+	// OutageRecovering is an outage whose fault is being removed.
+	OutageRecovering OutageStatus = "recovering"
+	// OutageDone is an outage that ended and whose nodes recovered.
+	OutageDone OutageStatus = "done"
+	// OutageFailed is an outage that ended with an error.
+	OutageFailed OutageStatus = "failed"
+)
+
+// Outage is a handle on a chaos action running in the background,
+// so that tests can check the cluster while the fault is in place.
+type Outage struct {
+	endCh   chan struct{}
+	endOnce sync.Once
+	doneCh  chan struct{}
+
+	mu     sync.Mutex
+	status OutageStatus
+	err    error
+}
+
+type outageKey struct{}
+
+// startOutage runs action in the background with an Outage attached to its ctx.
+func startOutage(ctx context.Context, action func(ctx context.Context) error) *Outage {
+	o := &Outage{
+		endCh:  make(chan struct{}),
+		doneCh: make(chan struct{}),
+		status: OutageStarting,
+	}
+	go func() {
+		defer close(o.doneCh)
+		err := action(context.WithValue(ctx, outageKey{}, o))
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.err = err
+		o.status = OutageDone
+		if err != nil {
+			o.status = OutageFailed
+		}
+	}()
+	return o
+}
+
+// outageFromContext returns the Outage attached to ctx, nil if there is none.
+func outageFromContext(ctx context.Context) *Outage {
+	o, _ := ctx.Value(outageKey{}).(*Outage)
+	return o
+}
+
+// Wait blocks until the outage is over and the nodes recovered, and returns the error of the action.
+func (o *Outage) Wait() error {
+	<-o.doneCh
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// End removes the fault before its duration passes and waits for the outage to be over.
+// Reboots can't be ended early, End only waits for them.
+func (o *Outage) End() error {
+	o.endOnce.Do(func() { close(o.endCh) })
+	return o.Wait()
+}
+
+// Status returns the current phase of the outage.
+func (o *Outage) Status() OutageStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.status
+}
+
+// setStatus moves the outage to status, unless it is nil.
+func (o *Outage) setStatus(status OutageStatus) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.status = status
+}
+
+// ended returns a channel closed once End is called, nil if o is nil.
+func (o *Outage) ended() <-chan struct{} {
+	if o == nil {
+		return nil
+	}
+	return o.endCh
+}
+
+// StartRebootNode reboots the node addressable with host in the background, see RebootNodeWithCtx.
+// The outage is active from the moment the node is down until it is booting again.
+func (cl *Cluster) StartRebootNode(ctx context.Context, host string, rebootDuration time.Duration, opts ...RebootOption) *Outage {
+	return startOutage(ctx, func(ctx context.Context) error {
+		_, err := cl.RebootNodeWithCtx(ctx, host, rebootDuration, opts...)
+		return err
+	})
+}
+
+// StartPartition partitions groupA from groupB in the background, see Partition.
+func (cl *Cluster) StartPartition(ctx context.Context, groupA, groupB []*v1.Node, duration time.Duration) *Outage {
+	return startOutage(ctx, func(ctx context.Context) error {
+		return cl.Partition(ctx, groupA, groupB, duration)
+	})
+}
+
+// StartStopService stops the systemd unit on node in the background, see StopService.
+func (cl *Cluster) StartStopService(ctx context.Context, node *v1.Node, unit string, duration time.Duration) *Outage {
+	return startOutage(ctx, func(ctx context.Context) error {
+		return cl.StopService(ctx, node, unit, duration)
+	})
+}
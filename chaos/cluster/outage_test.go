@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestOutageEnd(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := cluster.StartStopService(context.Background(), node, "kubelet.service", 1*time.Hour)
+	if err := wait.PollImmediate(1*time.Second, 1*time.Minute, func() (bool, error) {
+		return o.Status() == OutageActive, nil
+	}); err != nil {
+		t.Fatalf("outage is %s, expected %s", o.Status(), OutageActive)
+	}
+	if state, _, err := cluster.unitState(context.Background(), host, "kubelet.service"); err != nil || state == "active" {
+		t.Errorf("node: %s kubelet.service is %q during outage: %v", host, state, err)
+	}
+
+	if err := o.End(); err != nil {
+		t.Fatal(err)
+	}
+	if o.Status() != OutageDone {
+		t.Fatalf("outage is %s, expected %s", o.Status(), OutageDone)
+	}
+	if state, _, err := cluster.unitState(context.Background(), host, "kubelet.service"); err != nil || state != "active" {
+		t.Fatalf("node: %s kubelet.service is %q after outage: %v", host, state, err)
+	}
+}
@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// kubeletPort is the port of the kubelet API the API server connects to.
+const kubeletPort = 10250
+
+// Partition drops all traffic between the nodes in groupA and the nodes in groupB.
+// The partition is removed when duration passes or ctx is cancelled, whichever happens first.
+func (cl *Cluster) Partition(ctx context.Context, groupA, groupB []*v1.Node, duration time.Duration) error {
+	ab, err := partitionRules(groupA, groupB)
+	if err != nil {
+		return err
+	}
+	ba, err := partitionRules(groupB, groupA)
+	if err != nil {
+		return err
+	}
+
+	return cl.holdRules(ctx, append(ab, ba...), duration)
+}
+
+// PartitionOneWay drops all traffic sent from the nodes in `from` to the nodes in `to`.
+// Traffic sent from `to` to `from` is not affected.
+// The partition is removed when duration passes or ctx is cancelled, whichever happens first.
+func (cl *Cluster) PartitionOneWay(ctx context.Context, from, to []*v1.Node, duration time.Duration) error {
+	rules, err := partitionRules(from, to)
+	if err != nil {
+		return err
+	}
+
+	return cl.holdRules(ctx, rules, duration)
+}
+
+// IsolateNode drops all traffic between node and every other node of the cluster.
+// The partition is removed when duration passes or ctx is cancelled, whichever happens first.
+func (cl *Cluster) IsolateNode(ctx context.Context, node *v1.Node, duration time.Duration) error {
+	var others []*v1.Node
+	for _, n := range cl.allNodes() {
+		if n.GetName() == node.GetName() {
+			continue
+		}
+		others = append(others, n)
+	}
+	if len(others) < 1 {
+		return fmt.Errorf("node: %s no other nodes found to isolate from", node.GetName())
+	}
+
+	return cl.Partition(ctx, []*v1.Node{node}, others, duration)
+}
+
+// IsolateFromAPIServer drops the traffic between node and the API servers on the masters,
+// ie. requests to ports 443 and 6443 of the masters, from the node and from the pods on it,
+// and requests from the masters to the kubelet.
+// All other traffic, including the rest of the traffic with the masters, is not affected.
+// API servers reached through a load balancer are not isolated.
+// The partition is removed when duration passes or ctx is cancelled, whichever happens first.
+func (cl *Cluster) IsolateFromAPIServer(ctx context.Context, node *v1.Node, duration time.Duration) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	var ports []string
+	for _, p := range apiServerPorts {
+		ports = append(ports, strconv.Itoa(p))
+	}
+	var rules []iptablesRule
+	for _, m := range cl.Masters {
+		if m.GetName() == node.GetName() {
+			continue
+		}
+		for _, ip := range nodeIPs(m) {
+			toAPIServer := fmt.Sprintf("-d %s -p tcp -m multiport --dports %s -j DROP", ip, strings.Join(ports, ","))
+			rules = append(rules,
+				iptablesRule{
+					host:  host,
+					chain: "OUTPUT",
+					spec:  toAPIServer,
+				},
+				// Pods not using the host network reach the API server through FORWARD,
+				// once the `kubernetes` service IP is translated to the master.
+				iptablesRule{
+					host:  host,
+					chain: "FORWARD",
+					spec:  toAPIServer,
+				},
+				iptablesRule{
+					host:  host,
+					chain: "INPUT",
+					spec:  fmt.Sprintf("-s %s -p tcp --dport %d -j DROP", ip, kubeletPort),
+				},
+			)
+		}
+	}
+	if len(rules) < 1 {
+		return fmt.Errorf("node: %s no other masters found to isolate from", node.GetName())
+	}
+
+	return cl.holdRules(ctx, rules, duration)
+}
+
+// holdRules inserts rules, waits for duration or ctx cancellation and removes the rules.
+func (cl *Cluster) holdRules(ctx context.Context, rules []iptablesRule, duration time.Duration) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	ids, err := cl.insertRules(ctx, rules)
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("partition in place, holding for %s", duration)
+	hold(ctx, duration)
+
+	if err := cl.deleteRules(rules, ids); err != nil {
+		return err
+	}
+	glog.V(4).Infof("partition removed")
+	return nil
+}
+
+// partitionRules returns the rules that drop the traffic from `from` on every node in `to`.
+func partitionRules(from, to []*v1.Node) ([]iptablesRule, error) {
+	var rules []iptablesRule
+	for _, dst := range to {
+		host, err := hostForNode(dst)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range from {
+			if src.GetName() == dst.GetName() {
+				continue
+			}
+			for _, ip := range nodeIPs(src) {
+				rules = append(rules, iptablesRule{
+					host:  host,
+					chain: "INPUT",
+					spec:  fmt.Sprintf("-s %s -j DROP", ip),
+				})
+			}
+		}
+	}
+	if len(rules) < 1 {
+		return nil, fmt.Errorf("no nodes found that can be partitioned")
+	}
+
+	return rules, nil
+}
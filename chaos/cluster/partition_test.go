@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestIsolateNode(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := cluster.allNodes()
+	if len(nodes) < 2 {
+		t.Skip("need atleast 2 nodes to partition")
+	}
+	isolated, peer := nodes[len(nodes)-1], nodes[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.IsolateNode(ctx, isolated, 5*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	sshClient := utils.MustNewSSHClient(&utils.SSHConfig{Timeout: 10 * time.Second})
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, func() (bool, error) {
+		return !canPing(sshClient, isolated, peer), nil
+	}); err != nil {
+		t.Fatalf("node: %s can still reach node: %s", isolated.GetName(), peer.GetName())
+	}
+
+	cancel()
+	<-doneCh
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, func() (bool, error) {
+		return canPing(sshClient, isolated, peer), nil
+	}); err != nil {
+		t.Fatalf("node: %s can't reach node: %s after partition was removed", isolated.GetName(), peer.GetName())
+	}
+}
+
+func TestIsolateFromAPIServer(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 || len(cluster.Masters) < 1 {
+		t.Skip("need atleast 1 master and 1 worker")
+	}
+	isolated, master := cluster.Workers[0], cluster.Masters[0]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.IsolateFromAPIServer(ctx, isolated, 5*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := wait.PollImmediate(5*time.Second, 2*time.Minute, func() (bool, error) {
+		n, err := client.CoreV1().Nodes().Get(isolated.GetName(), metav1.GetOptions{})
+		return err == nil && !utils.IsNodeReady(n), nil
+	}); err != nil {
+		t.Errorf("node: %s still ready while isolated from the API server", isolated.GetName())
+	}
+	sshClient := utils.MustNewSSHClient(&utils.SSHConfig{Timeout: 10 * time.Second})
+	if !canPing(sshClient, isolated, master) {
+		t.Errorf("node: %s can't reach node: %s while isolated from the API server", isolated.GetName(), master.GetName())
+	}
+
+	cancel()
+	<-doneCh
+	if err := wait.PollImmediate(5*time.Second, 2*time.Minute, func() (bool, error) {
+		n, err := client.CoreV1().Nodes().Get(isolated.GetName(), metav1.GetOptions{})
+		return err == nil && utils.IsNodeReady(n), nil
+	}); err != nil {
+		t.Fatalf("node: %s not ready after partition was removed", isolated.GetName())
+	}
+}
+
+// canPing returns true if src can ping all the IPs of dst.
+func canPing(sshClient *utils.SSHClient, src, dst *v1.Node) bool {
+	host, err := hostForNode(src)
+	if err != nil {
+		return false
+	}
+	for _, ip := range nodeIPs(dst) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, _, err := sshClient.ExecWithCtx(ctx, host, fmt.Sprintf("ping -c 1 -W 2 %s", ip))
+		cancel()
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// cmdFindPIDsTpl prints the pids of the main processes of the kubernetes containers named `%[1]s`,
+// or of the processes named `%[1]s` if there are no such containers.
+const cmdFindPIDsTpl = `pids=$(sudo docker ps -q --filter name=k8s_%[1]s_ | xargs -r sudo docker inspect -f '{{.State.Pid}}'); [ -n "$pids" ] || pids=$(pgrep -x %[1]s); echo $pids`
+
+// validTarget matches the container and process names that can be targeted,
+// they are interpolated in shell cmds.
+var validTarget = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// PauseProcess freezes target on node with SIGSTOP for duration or until ctx is cancelled,
+// whichever happens first, and resumes it with SIGCONT.
+// target is the name of a kubernetes container eg. `kube-apiserver`, `etcd`, `kube-controller-manager`,
+// looked up with `docker ps`, or else the name of a process.
+// All the matching containers or processes on the node are frozen.
+func (cl *Cluster) PauseProcess(ctx context.Context, node *v1.Node, target string, duration time.Duration) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	pids, err := cl.findPIDs(ctx, host, target)
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("node: %s pausing %s pids: %s", host, target, pids)
+
+	action := "pause " + target
+	undo := fmt.Sprintf("sudo kill -CONT %s 2>/dev/null || true", pids)
+	id, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo kill -STOP %s", pids), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+	glog.V(4).Infof("node: %s %s paused, holding for %s", host, target, duration)
+	hold(ctx, duration)
+
+	return cl.restore(host, id, action, undo)
+}
+
+// findPIDs returns the space separated pids of target on host.
+func (cl *Cluster) findPIDs(ctx context.Context, host, target string) (string, error) {
+	if !validTarget.MatchString(target) {
+		return "", fmt.Errorf("invalid target %q", target)
+	}
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, fmt.Sprintf(cmdFindPIDsTpl, target))
+	if err != nil {
+		return "", fmt.Errorf("node: %s error finding %s: %v\nstdout:%s\nstderr:%s", host, target, err, stdout, stderr)
+	}
+	pids := strings.Join(strings.Fields(string(stdout)), " ")
+	if pids == "" {
+		return "", fmt.Errorf("node: %s no container or process found for %s", host, target)
+	}
+	return pids, nil
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestPauseProcess(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cluster.PauseProcess(context.Background(), node, "kubelet; true", 1*time.Minute); err == nil {
+		t.Fatal("expected invalid target to be refused")
+	}
+
+	pids, err := cluster.findPIDs(context.Background(), host, "kubelet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// `ps` reports the state `T` for stopped processes.
+	stopped := func() (bool, error) {
+		stdout, _, err := cluster.sshClient.Exec(host, fmt.Sprintf("ps -o stat= -p %s", strings.Replace(pids, " ", ",", -1)))
+		if err != nil {
+			return false, err
+		}
+		for _, stat := range strings.Fields(string(stdout)) {
+			if !strings.HasPrefix(stat, "T") {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.PauseProcess(context.Background(), node, "kubelet", 1*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, stopped); err != nil {
+		t.Fatalf("node: %s kubelet wasn't paused: %v", host, err)
+	}
+	<-doneCh
+
+	if paused, err := stopped(); err != nil || paused {
+		t.Fatalf("node: %s kubelet still paused after resume: %v", host, err)
+	}
+}
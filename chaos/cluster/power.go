@@ -0,0 +1,212 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+const (
+	// defaultPowerOffDuration is the default max time a node stays off with SSHPowerController.
+	defaultPowerOffDuration = 1 * time.Hour
+	// powerOnTimeout is the time allowed for a node to boot once it is powered on.
+	powerOnTimeout = 5 * time.Minute
+)
+
+// PowerController knows how to control the power of nodes.
+type PowerController interface {
+	// PowerOff turns the node off without a clean shutdown.
+	PowerOff(node *v1.Node) error
+	// PowerOn turns on a node that was turned off.
+	PowerOn(node *v1.Node) error
+	// Reset reboots the node without a clean shutdown.
+	Reset(node *v1.Node) error
+}
+
+// ShutdownNode turns off node using the Cluster's PowerController
+// and waits for the node to go down.
+func (cl *Cluster) ShutdownNode(node *v1.Node) error {
+	if err := cl.preflight(context.Background()); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	bootID, _, err := cl.bootInfo(context.Background(), host)
+	if err != nil {
+		return fmt.Errorf("node: %s error reading boot id: %v", host, err)
+	}
+
+	glog.V(4).Infof("node: %s powering off", host)
+	if err := cl.powerController.PowerOff(node); err != nil {
+		return fmt.Errorf("node: %s error powering off: %v", host, err)
+	}
+	if err := cl.waitForDown(context.Background(), host, bootID); err != nil {
+		return fmt.Errorf("node: %s didn't go down", host)
+	}
+	glog.V(4).Infof("node: %s is down", host)
+
+	return nil
+}
+
+// StartNode turns on node using the Cluster's PowerController
+// and waits for the node to come back up.
+func (cl *Cluster) StartNode(node *v1.Node) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	glog.V(4).Infof("node: %s powering on", host)
+	if err := cl.powerController.PowerOn(node); err != nil {
+		return fmt.Errorf("node: %s error powering on: %v", host, err)
+	}
+	if err := cl.waitForUp(context.Background(), host); err != nil {
+		return fmt.Errorf("node: %s didn't come back up", host)
+	}
+	glog.V(4).Infof("node: %s is up", host)
+
+	return nil
+}
+
+// SSHPowerController controls the power of nodes over ssh.
+//
+// A node can't be turned on over ssh, so PowerOff reboots the node
+// and stalls its boot with stall.service for at most OffDuration.
+// PowerOn waits for the stall to end and disables stall.service.
+type SSHPowerController struct {
+	// OffDuration is the max amount of time a node stays off.
+	OffDuration time.Duration
+
+	sshClient *utils.SSHClient
+	// journal is set by New to the Cluster's journal.
+	journal *journal
+
+	mu sync.Mutex
+	// stalls holds the journal entries of the enabled stall.services by host.
+	stalls map[string]string
+}
+
+// NewSSHPowerController returns a SSHPowerController that keeps nodes off for offDuration.
+func NewSSHPowerController(sshClient *utils.SSHClient, offDuration time.Duration) *SSHPowerController {
+	return &SSHPowerController{
+		OffDuration: offDuration,
+		sshClient:   sshClient,
+	}
+}
+
+// PowerOff reboots the node and stalls its boot for OffDuration.
+func (pc *SSHPowerController) PowerOff(node *v1.Node) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	glog.V(4).Infof("node: %s enabling stall.service", host)
+	id, err := enableStallService(context.Background(), pc.sshClient, pc.journal, host, pc.OffDuration)
+	pc.mu.Lock()
+	if pc.stalls == nil {
+		pc.stalls = make(map[string]string)
+	}
+	pc.stalls[host] = id
+	pc.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("node: %s error enabling stall.service: %v", host, err)
+	}
+	return kernelPanic(context.Background(), pc.sshClient, host)
+}
+
+// PowerOn waits for the node to come out of the stall and disables stall.service.
+func (pc *SSHPowerController) PowerOn(node *v1.Node) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	glog.V(4).Infof("node: %s waiting up to %s for stall.service to end", host, pc.OffDuration)
+	if err := wait.PollImmediate(10*time.Second, pc.OffDuration+powerOnTimeout, func() (bool, error) {
+		if _, _, err := pc.sshClient.Exec(host, "true"); err != nil {
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("node: %s didn't become reachable", host)
+	}
+
+	glog.V(4).Infof("node: %s disabling stall.service", host)
+	pc.mu.Lock()
+	id := pc.stalls[host]
+	pc.mu.Unlock()
+	if err := disableStallService(context.Background(), pc.sshClient, pc.journal, host, id); err != nil {
+		return fmt.Errorf("node: %s error disabling stall.service: %v", host, err)
+	}
+	pc.mu.Lock()
+	delete(pc.stalls, host)
+	pc.mu.Unlock()
+	return nil
+}
+
+// Reset reboots the node without stalling its boot.
+func (pc *SSHPowerController) Reset(node *v1.Node) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	return kernelPanic(context.Background(), pc.sshClient, host)
+}
+
+// VirshPowerController controls the power of libvirt/QEMU domains
+// using the `virsh` binary on the local machine.
+type VirshPowerController struct {
+	// URI is the libvirt connection URI eg. `qemu:///system`.
+	// Uses the virsh default if empty.
+	URI string
+	// DomainName maps a node to its libvirt domain.
+	// Defaults to the node name.
+	DomainName func(node *v1.Node) string
+}
+
+// PowerOff destroys ie. pulls the plug of the node's domain.
+func (pc *VirshPowerController) PowerOff(node *v1.Node) error {
+	return pc.virsh("destroy", node)
+}
+
+// PowerOn starts the node's domain.
+func (pc *VirshPowerController) PowerOn(node *v1.Node) error {
+	return pc.virsh("start", node)
+}
+
+// Reset resets the node's domain.
+func (pc *VirshPowerController) Reset(node *v1.Node) error {
+	return pc.virsh("reset", node)
+}
+
+func (pc *VirshPowerController) virsh(cmd string, node *v1.Node) error {
+	domain := node.GetName()
+	if pc.DomainName != nil {
+		domain = pc.DomainName(node)
+	}
+
+	var args []string
+	if pc.URI != "" {
+		args = append(args, "--connect", pc.URI)
+	}
+	args = append(args, cmd, domain)
+	glog.V(4).Infof("domain: %s executing cmd: 'virsh %v'", domain, args)
+	out, err := exec.Command("virsh", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("domain: %s virsh %s failed: %v\noutput:%s", domain, cmd, err, out)
+	}
+	return nil
+}
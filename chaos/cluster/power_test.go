@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+)
+
+func TestShutdownNode(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	sshClient := utils.MustNewSSHClient(&utils.SSHConfig{Timeout: 10 * time.Second})
+	cluster, err := New(client,
+		WithPowerController(NewSSHPowerController(sshClient, 2*time.Minute)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker to shutdown")
+	}
+	node := cluster.Workers[0]
+
+	if err := cluster.ShutdownNode(node); err != nil {
+		t.Fatal(err)
+	}
+	checkAllRebooting(t, hostsFromNodes(cluster.Workers[:1]))
+	if err := cluster.StartNode(node); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/coreos/ktestutil/utils"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
@@ -157,6 +158,151 @@ func TestRebootWorkers(t *testing.T) {
 	<-doneCh
 }
 
+func TestRebootNodeResult(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := hostsFromNodes(cluster.Workers)
+	if len(hosts) < 1 {
+		t.Skip("need atleast 1 worker to reboot")
+	}
+
+	res, err := cluster.RebootNodeWithCtx(context.Background(), hosts[0], 1*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BootID == "" || res.BootID == res.PrevBootID {
+		t.Fatalf("node: %s expected new boot id, got: %q previous: %q", res.Host, res.BootID, res.PrevBootID)
+	}
+	if res.BootTime < 1*time.Minute {
+		t.Fatalf("node: %s expected boot time to include reboot duration, got: %s", res.Host, res.BootTime)
+	}
+}
+
+func TestRebootMethods(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := hostsFromNodes(cluster.Workers)
+	if len(hosts) < 1 {
+		t.Skip("need atleast 1 worker to reboot")
+	}
+
+	for _, method := range []RebootMethod{RebootSystemctl, RebootSysrqReset} {
+		res, err := cluster.RebootNodeWithCtx(context.Background(), hosts[0], 30*time.Second, RebootWithMethod(method))
+		if err != nil {
+			t.Fatalf("method: %s %v", method, err)
+		}
+		if res.Method != method {
+			t.Fatalf("expected method: %s, got: %s", method, res.Method)
+		}
+	}
+}
+
+func TestRebootBootPerturbations(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts := hostsFromNodes(cluster.Workers)
+	if len(hosts) < 1 {
+		t.Skip("need atleast 1 worker to reboot")
+	}
+
+	delay := 1 * time.Minute
+	res, err := cluster.RebootNodeWithCtx(context.Background(), hosts[0], 30*time.Second, RebootWithBootPerturbations(
+		BootPerturbation{Unit: "docker.service", Delay: delay},
+		BootPerturbation{Unit: "kubelet.service", FailOnce: true},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.BootTime+res.KubeletTime < delay {
+		t.Fatalf("node: %s kubelet active %s after boot, expected docker delayed by %s", hosts[0], res.BootTime+res.KubeletTime, delay)
+	}
+}
+
+func TestRebootOrder(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client, WithRecoveryLevel(RecoveryNodeReady, 5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Masters) < 1 || len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 master and 1 worker to reboot")
+	}
+
+	results, err := cluster.RebootAllWithCtx(context.Background(), 30*time.Second,
+		RebootWithOrder(MastersFirst),
+		RebootWithHealthGate(AllGates(NodesReady, EtcdHealthy), 5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masters := len(hostsFromNodes(cluster.Masters))
+	for i, res := range results {
+		node := cluster.nodeForHost(res.Host)
+		if node == nil {
+			t.Fatalf("node: %s not found in cluster", res.Host)
+		}
+		if isMaster := cluster.isMaster(node); isMaster != (i < masters) {
+			t.Fatalf("node: %s rebooted at position: %d, master: %v", res.Host, i, isMaster)
+		}
+	}
+}
+
+func TestRebootZone(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client,
+		WithMaxDisruption(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zones := cluster.Zones()
+	if len(zones) < 1 {
+		t.Skip("need atleast 1 zone labelled node")
+	}
+	selector := labels.SelectorFromSet(labels.Set{ZoneLabel: zones[0]})
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if _, err := cluster.RebootZone(context.Background(), zones[0], 2*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var hosts []string
+	for _, n := range cluster.allNodes() {
+		if selector.Matches(labels.Set(n.GetLabels())) {
+			hosts = append(hosts, hostsFromNodes([]*v1.Node{n})...)
+		}
+	}
+	checkAllRebooting(t, hosts)
+	<-doneCh
+}
+
 func checkAllRebooting(t *testing.T, hosts []string) {
 	sshClient := utils.MustNewSSHClient(&utils.SSHConfig{Timeout: 10 * time.Second})
 	if err := wait.PollImmediate(10*time.Second, 3*time.Minute, func() (bool, error) {
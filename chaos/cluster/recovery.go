@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// defaultRecoveryTimeout is the default time allowed for a node to recover after kubelet is active.
+const defaultRecoveryTimeout = 5 * time.Minute
+
+// apiServerPorts are the ports the API servers on the masters may serve on.
+var apiServerPorts = []int{443, 6443}
+
+// RecoveryLevel defines how far a rebooted node must recover before its reboot is considered done.
+type RecoveryLevel int
+
+const (
+	// RecoveryKubelet waits for the kubelet unit to be active on the node.
+	RecoveryKubelet RecoveryLevel = iota
+	// RecoveryNodeReady also waits for the API server to see the node Ready with its new boot ID,
+	// for all the kube-system pods on the node to be Running and Ready and,
+	// for masters, for the API server on the master itself to answer healthz.
+	RecoveryNodeReady
+)
+
+// waitForRecovery waits for the node addressable with host to recover to level.
+func (cl *Cluster) waitForRecovery(ctx context.Context, host, bootID string, level RecoveryLevel) error {
+	if level < RecoveryNodeReady {
+		return nil
+	}
+
+	node := cl.nodeForHost(host)
+	if node == nil {
+		return fmt.Errorf("node: %s not found in cluster", host)
+	}
+	return poll(ctx, cl.rebootPollInterval, cl.recoveryTimeout, func() (bool, error) {
+		if err := cl.nodeRecovered(node, bootID); err != nil {
+			glog.V(4).Infof("node: %s not recovered yet: %v", host, err)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// nodeRecovered returns an error describing why node is not recovered yet.
+// If bootID is not empty, the node status must report it.
+func (cl *Cluster) nodeRecovered(node *v1.Node, bootID string) error {
+	n, err := cl.client.CoreV1().Nodes().Get(node.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if bootID != "" && n.Status.NodeInfo.BootID != bootID {
+		return fmt.Errorf("node status has boot id: %s, want: %s", n.Status.NodeInfo.BootID, bootID)
+	}
+	if !utils.IsNodeReady(n) {
+		return fmt.Errorf("node is not ready")
+	}
+
+	pods, err := cl.client.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", n.GetName()).String(),
+	})
+	if err != nil {
+		return err
+	}
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if !utils.IsPodReady(p) {
+			return fmt.Errorf("pod: %s/%s is not ready", p.GetNamespace(), p.GetName())
+		}
+	}
+
+	if cl.isMaster(n) {
+		if err := cl.apiServerHealthy(n); err != nil {
+			return fmt.Errorf("api server is not healthy: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// apiServerHealthy returns an error if the API server running on node doesn't answer healthz.
+// healthz is requested on the node itself, the API server the client uses might run on another master.
+func (cl *Cluster) apiServerHealthy(node *v1.Node) error {
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+	var cmds []string
+	for _, port := range apiServerPorts {
+		cmds = append(cmds, fmt.Sprintf("curl -sk --max-time 5 https://127.0.0.1:%d/healthz", port))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, strings.Join(cmds, " || "))
+	if err != nil {
+		return fmt.Errorf("node: %s healthz failed: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	if strings.TrimSpace(string(stdout)) != "ok" {
+		return fmt.Errorf("node: %s healthz returned: %q", host, stdout)
+	}
+	return nil
+}
+
+// nodeForHost returns the node addressable with host, nil if there is none.
+func (cl *Cluster) nodeForHost(host string) *v1.Node {
+	for _, n := range cl.allNodes() {
+		if utils.ExternalIP(n) == host {
+			return n
+		}
+	}
+	return nil
+}
+
+// isMaster returns true if node is one of the Cluster's masters.
+func (cl *Cluster) isMaster(node *v1.Node) bool {
+	for _, n := range cl.Masters {
+		if n.GetName() == node.GetName() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestNodeRecovered(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+
+	// a failed kube-system pod must not block the recovery of its node.
+	pod, err := client.CoreV1().Pods(metav1.NamespaceSystem).Create(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "ktestutil-failed-"},
+		Spec: v1.PodSpec{
+			NodeName:      node.GetName(),
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{{
+				Name:    "fail",
+				Image:   "busybox",
+				Command: []string{"false"},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CoreV1().Pods(metav1.NamespaceSystem).Delete(pod.GetName(), &metav1.DeleteOptions{})
+	if err := utils.Retry(30, 2*time.Second, func() error {
+		p, err := client.CoreV1().Pods(metav1.NamespaceSystem).Get(pod.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if p.Status.Phase != v1.PodFailed {
+			return fmt.Errorf("pod: %s is %s", p.GetName(), p.Status.Phase)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cluster.nodeRecovered(node, ""); err != nil {
+		t.Fatalf("node: %s not recovered: %v", node.GetName(), err)
+	}
+	if err := cluster.nodeRecovered(node, "not-the-boot-id"); err == nil {
+		t.Fatalf("node: %s recovered with a wrong boot id", node.GetName())
+	}
+	for _, m := range cluster.Masters {
+		if err := cluster.nodeRecovered(m, ""); err != nil {
+			t.Fatalf("master: %s not recovered: %v", m.GetName(), err)
+		}
+	}
+}
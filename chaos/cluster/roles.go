@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/coreos/ktestutil/utils"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// Role is the role of a node in the cluster.
+// Custom roles can be defined by a RoleClassifier.
+type Role string
+
+const (
+	// RoleMaster is the role of nodes running the control plane.
+	RoleMaster Role = "master"
+	// RoleWorker is the role of nodes running workloads.
+	RoleWorker Role = "worker"
+	// RoleEtcd is the role of nodes only running etcd.
+	RoleEtcd Role = "etcd"
+	// RoleOther is the role of nodes that can't be classified.
+	RoleOther Role = "other"
+)
+
+// RoleClassifier maps a node to its role.
+type RoleClassifier func(node *v1.Node) Role
+
+// DefaultRoleClassifier classifies nodes by their `node-role.kubernetes.io/{master,node,etcd}` labels.
+// Nodes without any of the labels are RoleOther.
+func DefaultRoleClassifier(node *v1.Node) Role {
+	switch {
+	case utils.IsMaster(node):
+		return RoleMaster
+	case utils.IsWorker(node):
+		return RoleWorker
+	case utils.IsEtcd(node):
+		return RoleEtcd
+	default:
+		return RoleOther
+	}
+}
+
+// Nodes returns the nodes with role.
+func (cl *Cluster) Nodes(role Role) []*v1.Node {
+	switch role {
+	case RoleMaster:
+		return cl.Masters
+	case RoleWorker:
+		return cl.Workers
+	default:
+		return cl.others[role]
+	}
+}
+
+// RebootRole reboots all the nodes with role that are accessible ie. have ExternalIP.
+func (cl *Cluster) RebootRole(ctx context.Context, role Role, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	hosts := hostsFromNodes(cl.Nodes(role))
+	if len(hosts) < 1 {
+		return nil, fmt.Errorf("no %s nodes found that can be rebooted", role)
+	}
+
+	glog.V(4).Infof("will reboot nodes: %s", hosts)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
+}
+
+// Roles returns the sorted roles of the cluster's nodes.
+func (cl *Cluster) Roles() []Role {
+	var roles []Role
+	if len(cl.Masters) > 0 {
+		roles = append(roles, RoleMaster)
+	}
+	if len(cl.Workers) > 0 {
+		roles = append(roles, RoleWorker)
+	}
+	roles = append(roles, cl.otherRoles()...)
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// otherRoles returns the sorted roles of the nodes that are neither masters nor workers.
+func (cl *Cluster) otherRoles() []Role {
+	var roles []Role
+	for role := range cl.others {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// containerRuntimeUnit is the unit of the container runtime on the nodes.
+const containerRuntimeUnit = "docker.service"
+
+// RuntimeRestartResult is the outcome of restarting the container runtime of a node.
+type RuntimeRestartResult struct {
+	// Host is the address of the node.
+	Host string
+	// Survived are the IDs of the containers running both before and after the restart.
+	Survived []string
+	// Lost are the IDs of the containers running before the restart but not after.
+	Lost []string
+	// RestartedPods maps the `namespace/name` of the pods on the node
+	// to the number of times their containers were restarted, for the pods that were.
+	RestartedPods map[string]int32
+}
+
+// RestartContainerRuntime restarts the container runtime on node
+// and waits for the node to be Ready with its kube-system pods.
+// It reports which containers survived the restart, eg. with docker live restore,
+// and which pods had their containers restarted.
+func (cl *Cluster) RestartContainerRuntime(ctx context.Context, node *v1.Node) (*RuntimeRestartResult, error) {
+	if err := cl.preflight(ctx); err != nil {
+		return nil, err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return nil, err
+	}
+	res := &RuntimeRestartResult{Host: host, RestartedPods: make(map[string]int32)}
+
+	before, err := cl.containerIDs(ctx, host)
+	if err != nil {
+		return res, err
+	}
+	restarts, err := cl.podRestarts(node)
+	if err != nil {
+		return res, err
+	}
+	_, pid, err := cl.unitState(ctx, host, containerRuntimeUnit)
+	if err != nil {
+		return res, err
+	}
+
+	action := "restart " + containerRuntimeUnit
+	undo := fmt.Sprintf("sudo systemctl start %s", containerRuntimeUnit)
+	entryID, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo systemctl restart %s", containerRuntimeUnit), undo)
+	if err != nil {
+		if rerr := cl.restore(host, entryID, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return res, err
+	}
+	if err := cl.waitForUnit(ctx, host, containerRuntimeUnit, pid); err != nil {
+		if rerr := cl.restore(host, entryID, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return res, fmt.Errorf("node: %s %s didn't restart: %v", host, containerRuntimeUnit, err)
+	}
+	if err := cl.journal.resolveID(entryID); err != nil {
+		return res, err
+	}
+
+	if err := poll(ctx, cl.rebootPollInterval, cl.recoveryTimeout, func() (bool, error) {
+		if err := cl.nodeRecovered(node, ""); err != nil {
+			glog.V(4).Infof("node: %s not recovered yet: %v", host, err)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return res, fmt.Errorf("node: %s didn't recover: %v", host, err)
+	}
+
+	after, err := cl.containerIDs(ctx, host)
+	if err != nil {
+		return res, err
+	}
+	for id := range before {
+		if after[id] {
+			res.Survived = append(res.Survived, id)
+		} else {
+			res.Lost = append(res.Lost, id)
+		}
+	}
+	sort.Strings(res.Survived)
+	sort.Strings(res.Lost)
+
+	cur, err := cl.podRestarts(node)
+	if err != nil {
+		return res, err
+	}
+	for pod, count := range cur {
+		if prev, ok := restarts[pod]; ok && count > prev {
+			res.RestartedPods[pod] = count - prev
+		}
+	}
+	glog.V(4).Infof("node: %s %d containers survived, %d lost, %d pods restarted", host, len(res.Survived), len(res.Lost), len(res.RestartedPods))
+	return res, nil
+}
+
+// containerIDs returns the IDs of the containers running on host.
+func (cl *Cluster) containerIDs(ctx context.Context, host string) (map[string]bool, error) {
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, "sudo docker ps -q --no-trunc")
+	if err != nil {
+		return nil, fmt.Errorf("node: %s error listing containers: %v\nstdout:%s\nstderr:%s", host, err, stdout, stderr)
+	}
+	ids := make(map[string]bool)
+	for _, id := range strings.Fields(string(stdout)) {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// podRestarts returns the restart counts of the containers of the pods on node,
+// summed per pod and keyed by `namespace/name`.
+func (cl *Cluster) podRestarts(node *v1.Node) (map[string]int32, error) {
+	pods, err := cl.client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.GetName()).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %v", err)
+	}
+	restarts := make(map[string]int32)
+	for _, p := range pods.Items {
+		var count int32
+		for _, s := range p.Status.ContainerStatuses {
+			count += s.RestartCount
+		}
+		restarts[p.GetNamespace()+"/"+p.GetName()] = count
+	}
+	return restarts, nil
+}
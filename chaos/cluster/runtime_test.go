@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/testworkload"
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRestartContainerRuntime(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	n, err := testworkload.NewNginx(client, metav1.NamespaceDefault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Delete()
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, pid, err := cluster.unitState(context.Background(), host, containerRuntimeUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := cluster.RestartContainerRuntime(context.Background(), node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, newPID, err := cluster.unitState(context.Background(), host, containerRuntimeUnit); err != nil || newPID == pid {
+		t.Fatalf("node: %s %s main pid: %s unchanged: %v", host, containerRuntimeUnit, pid, err)
+	}
+	if len(res.Survived)+len(res.Lost) < 1 {
+		t.Fatalf("node: %s no containers were running before the restart", host)
+	}
+	t.Logf("node: %s %d containers survived, %d lost, pods restarted: %v", host, len(res.Survived), len(res.Lost), res.RestartedPods)
+
+	if err := utils.Retry(10, 5*time.Second, n.IsReachable); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/ktestutil/chaos/safety"
+)
+
+func TestSafetyGuard(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client, WithSafetyGuard(safety.NewGuard(client, safety.NoCordonedNodes)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+
+	if _, err := cluster.setUnschedulable(node, true); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := cluster.setUnschedulable(node, false); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	err = cluster.StopService(context.Background(), node, "kubelet.service", 1*time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "refusing chaos") {
+		t.Fatalf("expected chaos to be refused on a cordoned cluster, got: %v", err)
+	}
+}
+
+func TestSafetyGuardDrainedBatch(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	// the nodes cordoned by the batch itself must not refuse the other reboots of the batch.
+	cluster, err := New(client,
+		WithDrain(5*time.Minute),
+		WithMaxDisruption(2),
+		WithSafetyGuard(safety.NewGuard(client, safety.NoCordonedNodes)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 2 {
+		t.Skip("need atleast 2 workers")
+	}
+	if _, err := cluster.RebootWorkersWithCtx(context.Background(), 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
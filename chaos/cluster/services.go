@@ -0,0 +1,120 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// serviceTimeout is the time allowed for a systemd unit to become active.
+const serviceTimeout = 2 * time.Minute
+
+// StopService stops the systemd unit on node for duration or until ctx is cancelled,
+// whichever happens first. It then starts the unit and waits for it to be active again.
+func (cl *Cluster) StopService(ctx context.Context, node *v1.Node, unit string, duration time.Duration) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	action := "stop " + unit
+	undo := fmt.Sprintf("sudo systemctl start %s", unit)
+	id, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo systemctl stop %s", unit), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+	glog.V(4).Infof("node: %s %s stopped, holding for %s", host, unit, duration)
+	hold(ctx, duration)
+
+	if err := cl.restore(host, id, action, undo); err != nil {
+		return err
+	}
+	return cl.waitForUnit(context.Background(), host, unit, "")
+}
+
+// KillService sends sig to the processes of the systemd unit on node,
+// and waits for the unit to be active with a new main process.
+// Units that systemd doesn't restart by themselves are started again.
+// sig must terminate the unit's main process.
+func (cl *Cluster) KillService(ctx context.Context, node *v1.Node, unit string, sig syscall.Signal) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+
+	_, pid, err := cl.unitState(ctx, host, unit)
+	if err != nil {
+		return err
+	}
+
+	action := "kill " + unit
+	undo := fmt.Sprintf("sudo systemctl start %s", unit)
+	id, err := cl.mutate(ctx, host, action, fmt.Sprintf("sudo systemctl kill --signal=%d %s", int(sig), unit), undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+
+	if err := cl.waitForUnit(ctx, host, unit, pid); err != nil {
+		glog.V(4).Infof("node: %s %s wasn't restarted by systemd: %v, starting it", host, unit, err)
+		if err := cl.restore(host, id, action, undo); err != nil {
+			return err
+		}
+		return cl.waitForUnit(context.Background(), host, unit, pid)
+	}
+	return cl.journal.resolveID(id)
+}
+
+// waitForUnit waits for unit to be active, with a main process other than prevPID if it's not empty.
+func (cl *Cluster) waitForUnit(ctx context.Context, host, unit, prevPID string) error {
+	return poll(ctx, cl.rebootPollInterval, serviceTimeout, func() (bool, error) {
+		state, pid, err := cl.unitState(ctx, host, unit)
+		if err != nil {
+			glog.Errorf("%v", err)
+			return false, nil
+		}
+		if state != "active" || (prevPID != "" && pid == prevPID) {
+			glog.V(4).Infof("node: %s %s is %s with main pid: %s", host, unit, state, pid)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// unitState returns the ActiveState and the MainPID of unit.
+func (cl *Cluster) unitState(ctx context.Context, host, unit string) (state, pid string, err error) {
+	cmd := fmt.Sprintf("systemctl show -p ActiveState -p MainPID %s", unit)
+	stdout, stderr, err := cl.sshClient.ExecWithCtx(ctx, host, cmd)
+	if err != nil {
+		return "", "", fmt.Errorf("node: %s error getting state of %s: %v\nstdout:%s\nstderr:%s", host, unit, err, stdout, stderr)
+	}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "ActiveState":
+			state = kv[1]
+		case "MainPID":
+			pid = kv[1]
+		}
+	}
+	return state, pid, nil
+}
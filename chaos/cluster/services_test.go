@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestStopService(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.StopService(context.Background(), node, "kubelet.service", 1*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, func() (bool, error) {
+		state, _, err := cluster.unitState(context.Background(), host, "kubelet.service")
+		return err == nil && state != "active", nil
+	}); err != nil {
+		t.Fatalf("node: %s kubelet.service wasn't stopped", host)
+	}
+	<-doneCh
+
+	if state, _, err := cluster.unitState(context.Background(), host, "kubelet.service"); err != nil || state != "active" {
+		t.Fatalf("node: %s kubelet.service is %q after restore: %v", host, state, err)
+	}
+}
+
+func TestKillService(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, pid, err := cluster.unitState(context.Background(), host, "kubelet.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.KillService(context.Background(), node, "kubelet.service", syscall.SIGKILL); err != nil {
+		t.Fatal(err)
+	}
+	_, newPID, err := cluster.unitState(context.Background(), host, "kubelet.service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPID == pid {
+		t.Fatalf("node: %s kubelet.service main pid unchanged: %s", host, pid)
+	}
+}
@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// stressUnitPrefix prefixes the names of the transient scopes the stress runs in.
+// Each Stress gets its own scope, so concurrent stresses of a node don't collide.
+const stressUnitPrefix = "ktestutil-chaos-stress-"
+
+// StressSpec defines the load put on a node.
+type StressSpec struct {
+	// CPUs is the number of CPU burners to run.
+	CPUs int
+	// CPUQuota limits the CPU time of the scope eg. `150%` for one and a half CPUs.
+	// The scope is not limited if empty.
+	CPUQuota string
+	// Memory is the quantity of memory to allocate eg. `2Gi`.
+	Memory string
+	// MemoryLimit limits the memory of the scope eg. `3Gi`.
+	// The scope is not limited if empty.
+	MemoryLimit string
+}
+
+// script returns the shell script running the stress until deadline passes.
+// deadline is rounded up to whole seconds, as `timeout 0` would never time out.
+func (s StressSpec) script(deadline time.Duration) (string, error) {
+	secs := int64((deadline + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	var cmds []string
+	for i := 0; i < s.CPUs; i++ {
+		cmds = append(cmds, fmt.Sprintf("timeout %d sh -c 'while :; do :; done' &", secs))
+	}
+	if s.Memory != "" {
+		q, err := resource.ParseQuantity(s.Memory)
+		if err != nil {
+			return "", fmt.Errorf("invalid memory %q: %v", s.Memory, err)
+		}
+		// tail holds everything until EOF as there are no newlines.
+		cmds = append(cmds, fmt.Sprintf("{ head -c %d /dev/zero; sleep %d; } | tail >/dev/null &", q.Value(), secs))
+	}
+	if len(cmds) < 1 {
+		return "", fmt.Errorf("stress spec doesn't stress anything")
+	}
+	return strings.Join(append(cmds, "wait"), " "), nil
+}
+
+// properties returns the systemd-run properties limiting the scope.
+func (s StressSpec) properties() (string, error) {
+	var props []string
+	if s.CPUQuota != "" {
+		props = append(props, "-p CPUQuota="+s.CPUQuota)
+	}
+	if s.MemoryLimit != "" {
+		q, err := resource.ParseQuantity(s.MemoryLimit)
+		if err != nil {
+			return "", fmt.Errorf("invalid memory limit %q: %v", s.MemoryLimit, err)
+		}
+		props = append(props, fmt.Sprintf("-p MemoryLimit=%d", q.Value()))
+	}
+	return strings.Join(props, " "), nil
+}
+
+// Stress runs CPU burners and memory hogs on node in a transient systemd scope
+// for duration or until ctx is cancelled, whichever happens first.
+// The processes in the scope exit by themselves once duration passes,
+// even if the scope can't be stopped.
+func (cl *Cluster) Stress(ctx context.Context, node *v1.Node, spec StressSpec, duration time.Duration) error {
+	if err := cl.preflight(ctx); err != nil {
+		return err
+	}
+	host, err := hostForNode(node)
+	if err != nil {
+		return err
+	}
+	script, err := spec.script(duration)
+	if err != nil {
+		return err
+	}
+	props, err := spec.properties()
+	if err != nil {
+		return err
+	}
+
+	unit := stressUnitPrefix + utilrand.String(5)
+	action := "stress " + unit
+	cmd := fmt.Sprintf(`nohup sudo systemd-run --scope --unit=%s %s sh -c "%s" >/dev/null 2>&1 &`, unit, props, script)
+	undo := fmt.Sprintf("sudo systemctl stop %s.scope 2>/dev/null || true", unit)
+	id, err := cl.mutate(ctx, host, action, cmd, undo)
+	if err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return err
+	}
+	if err := cl.waitForUnit(ctx, host, unit+".scope", ""); err != nil {
+		if rerr := cl.restore(host, id, action, undo); rerr != nil {
+			glog.Errorf("error cleaning up %s: %v", action, rerr)
+		}
+		return fmt.Errorf("node: %s stress didn't start: %v", host, err)
+	}
+	glog.V(4).Infof("node: %s stressed, holding for %s", host, duration)
+	hold(ctx, duration)
+
+	return cl.restore(host, id, action, undo)
+}
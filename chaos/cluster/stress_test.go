@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestStress(t *testing.T) {
+	if err := ready(client); err != nil {
+		t.Fatalf("cluster not ready: %v", err)
+	}
+
+	cluster, err := New(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cluster.Workers) < 1 {
+		t.Skip("need atleast 1 worker")
+	}
+	node := cluster.Workers[0]
+	host, err := hostForNode(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// activeScopes returns the stress scopes active on the node.
+	activeScopes := func() ([]string, error) {
+		stdout, _, err := cluster.sshClient.Exec(host, fmt.Sprintf("systemctl list-units --state=active --no-legend --plain '%s*.scope'", stressUnitPrefix))
+		if err != nil {
+			return nil, err
+		}
+		var scopes []string
+		for _, line := range strings.Split(strings.TrimSpace(string(stdout)), "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 {
+				scopes = append(scopes, fields[0])
+			}
+		}
+		return scopes, nil
+	}
+
+	spec := StressSpec{CPUs: 2, CPUQuota: "150%", Memory: "256Mi", MemoryLimit: "512Mi"}
+	doneCh := make(chan struct{})
+	go func() {
+		defer func() { doneCh <- struct{}{} }()
+		if err := cluster.Stress(context.Background(), node, spec, 1*time.Minute); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := wait.PollImmediate(5*time.Second, 1*time.Minute, func() (bool, error) {
+		scopes, err := activeScopes()
+		return err == nil && len(scopes) > 0, nil
+	}); err != nil {
+		t.Fatalf("node: %s stress scope never became active", host)
+	}
+	<-doneCh
+
+	if scopes, err := activeScopes(); err != nil || len(scopes) > 0 {
+		t.Fatalf("node: %s stress scopes %v active after restore: %v", host, scopes, err)
+	}
+}
@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// ZoneLabel is the node label holding the node's availability zone.
+const ZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+// RebootSelected reboots all the nodes whose labels match selector.
+func (cl *Cluster) RebootSelected(ctx context.Context, selector labels.Selector, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	var nodes []*v1.Node
+	for _, n := range cl.allNodes() {
+		if selector.Matches(labels.Set(n.GetLabels())) {
+			nodes = append(nodes, n)
+		}
+	}
+	hosts := hostsFromNodes(nodes)
+	if len(hosts) < 1 {
+		return nil, fmt.Errorf("no nodes found matching %q that can be rebooted", selector.String())
+	}
+
+	glog.V(4).Infof("will reboot nodes: %s", hosts)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
+}
+
+// RebootNodes reboots the nodes with the given names.
+// It fails without rebooting any node if a name is not part of the cluster.
+func (cl *Cluster) RebootNodes(ctx context.Context, names []string, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	byName := make(map[string]*v1.Node)
+	for _, n := range cl.allNodes() {
+		byName[n.GetName()] = n
+	}
+	var (
+		nodes   []*v1.Node
+		missing []string
+	)
+	for _, name := range names {
+		n, ok := byName[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("nodes: %s not found in cluster", strings.Join(missing, ", "))
+	}
+	hosts := hostsFromNodes(nodes)
+	if len(hosts) < 1 {
+		return nil, fmt.Errorf("no nodes found that can be rebooted")
+	}
+
+	glog.V(4).Infof("will reboot nodes: %s", hosts)
+	return cl.rebootHosts(ctx, hosts, rebootDuration, opts...)
+}
+
+// RebootZone simulates the outage of an availability zone
+// by rebooting all the nodes in zone at once, ignoring MaxDisruption.
+func (cl *Cluster) RebootZone(ctx context.Context, zone string, rebootDuration time.Duration, opts ...RebootOption) ([]*RebootResult, error) {
+	selector := labels.SelectorFromSet(labels.Set{ZoneLabel: zone})
+	opts = append(opts, RebootWithMaxDisruption("100%"))
+	return cl.RebootSelected(ctx, selector, rebootDuration, opts...)
+}
+
+// Zones returns the sorted availability zones of the cluster's nodes.
+func (cl *Cluster) Zones() []string {
+	seen := make(map[string]bool)
+	var zones []string
+	for _, n := range cl.allNodes() {
+		zone, ok := n.GetLabels()[ZoneLabel]
+		if !ok || seen[zone] {
+			continue
+		}
+		seen[zone] = true
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
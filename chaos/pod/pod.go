@@ -18,6 +18,8 @@ import (
 	"context"
 	"math/rand"
 
+	"github.com/coreos/ktestutil/chaos/safety"
+
 	"github.com/golang/glog"
 	"golang.org/x/time/rate"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,6 +49,10 @@ type CrushConfig struct {
 	KillProbability float64
 	// KillMax is the max number of selected pods to crush.
 	KillMax int
+
+	// Guard, if set, must verify the cluster before every crush,
+	// crushes are skipped while the cluster is unsafe.
+	Guard *safety.Guard
 }
 
 // TODO: respect context in k8s operations.
@@ -70,6 +76,11 @@ func (m *Monkey) CrushPods(ctx context.Context, c *CrushConfig) {
 			continue
 		}
 
+		if err := c.Guard.Verify(ctx); err != nil {
+			glog.Warningf("refusing to kill pods for selector %v: %v", ls, err)
+			continue
+		}
+
 		pods, err := m.kubecli.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: ls})
 		if err != nil {
 			glog.Errorf("failed to list pods for selector %v: %v", ls, err)
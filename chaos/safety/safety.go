@@ -0,0 +1,182 @@
+// Package safety verifies that a cluster is healthy enough to survive more chaos.
+package safety
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/ktestutil/utils"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+// ControlPlaneSelector selects the pods of a self-hosted control plane in kube-system, as deployed by bootkube.
+const ControlPlaneSelector = "tier=control-plane"
+
+// Check returns an error describing the invariant the cluster violates, if any.
+// Checks don't fail on the nodes ctx expects to be disrupted, see WithExpectedDisruption.
+type Check func(ctx context.Context, client kubernetes.Interface) error
+
+type expectedDisruptionKey struct{}
+
+// WithExpectedDisruption returns a copy of ctx expecting nodes, by name, to be disrupted eg. cordoned or not Ready,
+// by the chaos action verifying the guard.
+func WithExpectedDisruption(ctx context.Context, nodes ...string) context.Context {
+	expected := make(map[string]bool)
+	for name := range expectedDisruption(ctx) {
+		expected[name] = true
+	}
+	for _, name := range nodes {
+		expected[name] = true
+	}
+	return context.WithValue(ctx, expectedDisruptionKey{}, expected)
+}
+
+// DisruptionExpected returns whether ctx expects node, by name, to be disrupted.
+func DisruptionExpected(ctx context.Context, node string) bool {
+	return expectedDisruption(ctx)[node]
+}
+
+func expectedDisruption(ctx context.Context) map[string]bool {
+	expected, _ := ctx.Value(expectedDisruptionKey{}).(map[string]bool)
+	return expected
+}
+
+// Guard refuses chaos on a cluster that violates any of its checks.
+type Guard struct {
+	client kubernetes.Interface
+	checks []Check
+}
+
+// NewGuard creates a Guard verifying checks, or DefaultChecks if none are given.
+func NewGuard(client kubernetes.Interface, checks ...Check) *Guard {
+	if len(checks) < 1 {
+		checks = DefaultChecks()
+	}
+	return &Guard{client: client, checks: checks}
+}
+
+// DefaultChecks returns checks for atleast 1 Ready node, a healthy etcd quorum,
+// Ready control plane pods selected by ControlPlaneSelector, if any, and no cordoned nodes.
+func DefaultChecks() []Check {
+	return []Check{MinReadyNodes(1), EtcdQuorum, ControlPlaneReady(ControlPlaneSelector), NoCordonedNodes}
+}
+
+// Verify runs all the checks, it returns an error describing every violated invariant.
+// It stops once ctx is cancelled. A nil Guard verifies nothing.
+func (g *Guard) Verify(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	var errs []error
+	for _, check := range g.checks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := check(ctx, g.client); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cluster is unsafe for chaos: %v", errors.NewAggregate(errs))
+	}
+	return nil
+}
+
+// MinReadyNodes checks that atleast min nodes are Ready, counting the nodes expected to be disrupted as Ready.
+func MinReadyNodes(min int) Check {
+	return func(ctx context.Context, client kubernetes.Interface) error {
+		nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("error listing nodes: %v", err)
+		}
+		var ready int
+		for i := range nodes.Items {
+			if n := &nodes.Items[i]; utils.IsNodeReady(n) || DisruptionExpected(ctx, n.GetName()) {
+				ready++
+			}
+		}
+		if ready < min {
+			return fmt.Errorf("%d nodes are ready, need atleast %d", ready, min)
+		}
+		return nil
+	}
+}
+
+// EtcdQuorum checks that a majority of the etcd members are healthy according to the API server.
+func EtcdQuorum(ctx context.Context, client kubernetes.Interface) error {
+	statuses, err := client.CoreV1().ComponentStatuses().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing component statuses: %v", err)
+	}
+	var (
+		members   int
+		unhealthy []string
+	)
+	for _, cs := range statuses.Items {
+		if !strings.HasPrefix(cs.GetName(), "etcd-") {
+			continue
+		}
+		members++
+		healthy := false
+		for _, c := range cs.Conditions {
+			if c.Type == v1.ComponentHealthy && c.Status == v1.ConditionTrue {
+				healthy = true
+			}
+		}
+		if !healthy {
+			unhealthy = append(unhealthy, cs.GetName())
+		}
+	}
+	if members < 1 {
+		return fmt.Errorf("no etcd component status found")
+	}
+	if healthy := members - len(unhealthy); healthy <= members/2 {
+		return fmt.Errorf("etcd has no quorum: %d of %d members healthy, unhealthy: %s", healthy, members, strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+// ControlPlaneReady checks that all the control plane pods in kube-system, selected by selector, are Ready.
+// Pods on nodes expected to be disrupted are skipped.
+// It passes if no pods match, eg. on clusters whose control plane isn't self-hosted.
+func ControlPlaneReady(selector string) Check {
+	return func(ctx context.Context, client kubernetes.Interface) error {
+		pods, err := client.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return fmt.Errorf("error listing control plane pods: %v", err)
+		}
+		var notReady []string
+		for i := range pods.Items {
+			if p := &pods.Items[i]; !utils.IsPodReady(p) && !DisruptionExpected(ctx, p.Spec.NodeName) {
+				notReady = append(notReady, p.GetName())
+			}
+		}
+		if len(notReady) > 0 {
+			return fmt.Errorf("control plane pods: %s are not ready", strings.Join(notReady, ", "))
+		}
+		return nil
+	}
+}
+
+// NoCordonedNodes checks that no node is cordoned, apart from the nodes expected to be disrupted.
+func NoCordonedNodes(ctx context.Context, client kubernetes.Interface) error {
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %v", err)
+	}
+	var cordoned []string
+	for _, n := range nodes.Items {
+		if n.Spec.Unschedulable && !DisruptionExpected(ctx, n.GetName()) {
+			cordoned = append(cordoned, n.GetName())
+		}
+	}
+	if len(cordoned) > 0 {
+		return fmt.Errorf("nodes: %s are cordoned", strings.Join(cordoned, ", "))
+	}
+	return nil
+}
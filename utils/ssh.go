@@ -156,3 +156,15 @@ func NewScpClient(sshClient *SSHClient, host string) (*sftp.Client, error) {
 
 	return sftp.NewClient(client)
 }
+
+// NewTunnel returns a ssh connection to host, whose Dial method opens connections from host.
+// It can be used eg. as the Dial func of a http.Transport to reach endpoints only reachable from host.
+// The caller must close it.
+func NewTunnel(sshClient *SSHClient, host string) (*ssh.Client, error) {
+	if host == "" {
+		return nil, fmt.Errorf("error: empty host provided")
+	}
+
+	endpoint := fmt.Sprintf("%s:%d", host, sshClient.port)
+	return ssh.Dial("tcp", endpoint, sshClient.ClientConfig)
+}
@@ -11,6 +11,8 @@ const (
 	NodeRoleMasterLabel = "node-role.kubernetes.io/master"
 	// NodeRoleWorkerLabel defines the worker node's label.
 	NodeRoleWorkerLabel = "node-role.kubernetes.io/node"
+	// NodeRoleEtcdLabel defines the etcd node's label.
+	NodeRoleEtcdLabel = "node-role.kubernetes.io/etcd"
 )
 
 // Retry retries f until f return nil error.
@@ -44,6 +46,12 @@ func IsWorker(n *v1.Node) bool {
 	return ok
 }
 
+// IsEtcd returns true if the node's labels contains "node-role.kubernetes.io/etcd".
+func IsEtcd(n *v1.Node) bool {
+	_, ok := n.Labels[NodeRoleEtcdLabel]
+	return ok
+}
+
 // ExternalIP returns external IP for a node.
 // Will be empty string if not External IP found for node.
 func ExternalIP(n *v1.Node) string {
@@ -56,3 +64,26 @@ func ExternalIP(n *v1.Node) string {
 	}
 	return host
 }
+
+// IsNodeReady returns true if the node's NodeReady condition is true.
+func IsNodeReady(n *v1.Node) bool {
+	for _, condition := range n.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// IsPodReady returns true if the pod is running and its PodReady condition is true.
+func IsPodReady(p *v1.Pod) bool {
+	if p.Status.Phase != v1.PodRunning {
+		return false
+	}
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}